@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DeleteAccountRequest represents the request payload for deleting the
+// caller's account
+// @swagger:model
+type DeleteAccountRequest struct {
+	// required: true
+	// example: securePassword123!
+	Password string `json:"password"`
+}
+
+// DeleteAccountResponse tells the user when their account will be deleted
+// @swagger:model
+type DeleteAccountResponse struct {
+	// Logging in again before this time cancels the deletion
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
+
+// deactivateAccountHandler godoc
+// @Summary Deactivate account
+// @Description Hide the caller's profile and matches from everyone and sign them out everywhere. Logging in again reactivates the account.
+// @Tags users
+// @Security ApiKeyAuth
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /user/deactivate [post]
+func deactivateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := deactivateAccount(currentUserID(r), nil); err != nil {
+		http.Error(w, "Failed to deactivate account", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteAccountHandler godoc
+// @Summary Delete account
+// @Description Deactivate the caller's account and permanently delete it, with their profile, photos, matches and messages, once the grace period has passed. Reports involving the caller, and messages a moderator still needs to review, are kept. Logging in again before then cancels the deletion.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param account body DeleteAccountRequest true "Password confirmation"
+// @Success 202 {object} DeleteAccountResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /user [delete]
+func deleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var req DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	userID := currentUserID(r)
+	user, err := userRepo.FindByID(userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !checkPassword(user.PasswordHash, req.Password) {
+		http.Error(w, "Password is incorrect", http.StatusForbidden)
+		return
+	}
+
+	deleteAt := time.Now().Add(cfg.AccountDeletionGrace)
+	if err := deactivateAccount(userID, &deleteAt); err != nil {
+		http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(DeleteAccountResponse{DeletionScheduledAt: deleteAt})
+}
+
+// deactivateAccount deactivates the user's own account and signs them out
+// everywhere, scheduling its deletion if deleteAt is set
+func deactivateAccount(userID uint, deleteAt *time.Time) error {
+	if err := userRepo.Deactivate(userID, deleteAt); err != nil {
+		return err
+	}
+	activeUsers.Delete(userID)
+	if err := sessionRepo.RevokeAllForUser(userID); err != nil {
+		return err
+	}
+	chatHub.Disconnect(userID)
+	return nil
+}
+
+// runAccountPurge deletes accounts whose deletion grace period has passed,
+// and the archives of expired data exports, every interval. It never
+// returns, so start it in its own goroutine.
+func runAccountPurge(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		users, err := userRepo.FindDueForDeletion(time.Now())
+		if err != nil {
+			log.Printf("Account purge failed: %v", err)
+		}
+		for _, user := range users {
+			if err := purgeAccount(context.Background(), user.ID); err != nil {
+				log.Printf("Failed to purge account %d: %v", user.ID, err)
+				continue
+			}
+			log.Printf("Purged account %d", user.ID)
+		}
+		purgeExpiredDataExports(context.Background())
+		<-ticker.C
+	}
+}
+
+// purgeAccount permanently deletes a user and what is stored about them,
+// apart from what repositories.UserRepository.Delete keeps for moderators.
+// Photos and export archives go first: if the database delete then fails,
+// the next run retries it, whereas photos left behind by a deleted user
+// could never be found.
+func purgeAccount(ctx context.Context, userID uint) error {
+	profile, err := profileRepo.FindByUserID(userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err == nil {
+		for _, url := range profile.Photos {
+			deletePhotoBlobs(ctx, userID, photoIDFromURL(url))
+		}
+	}
+	if err := deleteDataExportBlobs(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := userRepo.Delete(userID); err != nil {
+		return err
+	}
+	activeUsers.Delete(userID)
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/connectplus/cache"
+)
+
+// activeUsers caches whether each user's account is active, so that
+// authMiddleware doesn't load the user on every request
+var activeUsers *cache.TTL[uint, bool]
+
+// isUserActive reports whether the user's account is active. It returns
+// gorm.ErrRecordNotFound if the user doesn't exist.
+func isUserActive(userID uint) (bool, error) {
+	if active, ok := activeUsers.Get(userID); ok {
+		return active, nil
+	}
+	user, err := userRepo.FindByID(userID)
+	if err != nil {
+		return false, err
+	}
+	activeUsers.Set(userID, user.IsActive)
+	return user.IsActive, nil
+}
+
+// setUserActive suspends or reactivates a user. Suspended users are signed
+// out everywhere at once, including open chat connections.
+func setUserActive(userID uint, active bool) error {
+	if err := userRepo.SetActive(userID, active); err != nil {
+		return err
+	}
+	activeUsers.Delete(userID)
+	if !active {
+		if err := sessionRepo.RevokeAllForUser(userID); err != nil {
+			return err
+		}
+		chatHub.Disconnect(userID)
+	}
+	return nil
+}
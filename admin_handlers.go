@@ -0,0 +1,693 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/connectplus/models"
+	"github.com/connectplus/repositories"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAdminListLimit = 50
+	maxAdminListLimit     = 200
+)
+
+// Audit log actions and target types. Every request to the admin API that
+// reads user data or changes anything is recorded.
+const (
+	auditListReports        = "list_reports"
+	auditReviewReport       = "review_report"
+	auditViewReportMessages = "view_report_messages"
+	auditViewMatchMessages  = "view_match_messages"
+	auditViewUser           = "view_user"
+	auditSuspendUser        = "suspend_user"
+	auditReactivateUser     = "reactivate_user"
+	auditSetRole            = "set_role"
+	auditTargetReport       = "report"
+	auditTargetMatch        = "match"
+	auditTargetUser         = "user"
+)
+
+// AdminMessageResponse represents a message as seen by moderators, including
+// messages hidden from the participants
+// @swagger:model
+type AdminMessageResponse struct {
+	MessageResponse
+
+	// When the message was hidden from the participants, if it was
+	HiddenAt *time.Time `json:"hidden_at,omitempty"`
+}
+
+// AdminReportResponse represents a report in the moderation queue
+// @swagger:model
+type AdminReportResponse struct {
+	// example: 5
+	ID uint `json:"id"`
+
+	// example: 1
+	ReporterID uint `json:"reporter_id"`
+
+	// example: 2
+	ReportedID uint `json:"reported_id"`
+
+	// example: harassment
+	Reason models.ReportReason `json:"reason"`
+
+	Details string `json:"details"`
+
+	// One of "open", "resolved" or "dismissed"
+	// example: open
+	Status models.ReportStatus `json:"status"`
+
+	// Moderator who closed the report
+	ReviewerID *uint      `json:"reviewer_id,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newAdminReportResponse(report *models.Report) AdminReportResponse {
+	return AdminReportResponse{
+		ID:         report.ID,
+		ReporterID: report.ReporterID,
+		ReportedID: report.ReportedID,
+		Reason:     report.Reason,
+		Details:    report.Details,
+		Status:     report.Status,
+		ReviewerID: report.ReviewerID,
+		ReviewedAt: report.ReviewedAt,
+		CreatedAt:  report.CreatedAt,
+	}
+}
+
+// ReviewReportRequest represents the request payload for closing a report
+// @swagger:model
+type ReviewReportRequest struct {
+	// Either "resolved" or "dismissed"
+	// required: true
+	// example: resolved
+	Status models.ReportStatus `json:"status"`
+
+	// Why, recorded in the audit log
+	// example: Suspended the reported user
+	Note string `json:"note"`
+}
+
+// AdminUserResponse represents a user account as seen by moderators
+// @swagger:model
+type AdminUserResponse struct {
+	// example: 2
+	ID uint `json:"id"`
+
+	// example: jane@example.com
+	Email string `json:"email"`
+
+	// One of "user", "moderator" or "admin"
+	// example: user
+	Role models.Role `json:"role"`
+
+	// False while the user is suspended
+	IsActive   bool `json:"is_active"`
+	IsVerified bool `json:"is_verified"`
+
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+
+	// Set while the user has deactivated their own account
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+
+	// When the user's account will be deleted at their request
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+
+	// The user's profile, including the birth date, if they created one
+	Profile *ProfileResponse `json:"profile,omitempty"`
+}
+
+func newAdminUserResponse(user *models.User) AdminUserResponse {
+	return AdminUserResponse{
+		ID:          user.ID,
+		Email:       user.Email,
+		Role:        user.Role,
+		IsActive:    user.IsActive,
+		IsVerified:  user.IsVerified,
+		CreatedAt:   user.CreatedAt,
+		LastLoginAt: user.LastLoginAt,
+
+		DeactivatedAt:       user.DeactivatedAt,
+		DeletionScheduledAt: user.DeletionScheduledAt,
+	}
+}
+
+// SuspendUserRequest represents the request payload for suspending a user
+// @swagger:model
+type SuspendUserRequest struct {
+	// Why, recorded in the audit log
+	// example: Harassment confirmed in report 5
+	Reason string `json:"reason"`
+}
+
+// SetRoleRequest represents the request payload for changing a user's role
+// @swagger:model
+type SetRoleRequest struct {
+	// One of "user", "moderator" or "admin"
+	// required: true
+	// example: moderator
+	Role models.Role `json:"role"`
+}
+
+// AuditLogResponse represents one audit log entry
+// @swagger:model
+type AuditLogResponse struct {
+	ID uint `json:"id"`
+
+	// Moderator or admin who acted
+	// example: 1
+	ActorID uint `json:"actor_id"`
+
+	// example: suspend_user
+	Action string `json:"action"`
+
+	// example: user
+	TargetType string `json:"target_type"`
+
+	// example: 2
+	TargetID uint `json:"target_id"`
+
+	Details string `json:"details,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// promoteAdmins gives the admin role to the configured admin users
+func promoteAdmins(userIDs []uint) {
+	for _, userID := range userIDs {
+		if err := userRepo.SetRole(userID, models.RoleAdmin); err != nil {
+			log.Printf("Failed to make user %d an admin: %v", userID, err)
+		}
+	}
+}
+
+// audit records an admin action. Failing to record it doesn't fail the
+// request, since the action has already happened.
+func audit(r *http.Request, action, targetType string, targetID uint, details string) {
+	entry := &models.AuditLog{
+		ActorID:    currentUserID(r),
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    details,
+	}
+	if err := auditLogRepo.Create(entry); err != nil {
+		log.Printf("Failed to write audit log entry %s %s %d by user %d: %v",
+			action, targetType, targetID, entry.ActorID, err)
+	}
+}
+
+// pathID parses a positive ID from the named path value, writing a 400
+// response with the given name if it is invalid
+func pathID(w http.ResponseWriter, r *http.Request, name, label string) (uint, bool) {
+	id, err := strconv.ParseUint(r.PathValue(name), 10, 64)
+	if err != nil || id == 0 {
+		http.Error(w, "Invalid "+label+" ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// adminListReportsHandler godoc
+// @Summary List reports
+// @Description Get reports in the moderation queue, oldest first. Moderator only.
+// @Tags admin
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param status query string false "One of open, resolved or dismissed (default open)"
+// @Param limit query int false "Maximum number of reports to return (default 50, max 200)"
+// @Param offset query int false "Number of reports to skip"
+// @Success 200 {array} AdminReportResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/reports [get]
+func adminListReportsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status := models.ReportOpen
+	if v := r.URL.Query().Get("status"); v != "" {
+		switch s := models.ReportStatus(v); s {
+		case models.ReportOpen, models.ReportResolved, models.ReportDismissed:
+			status = s
+		default:
+			http.Error(w, "Invalid status", http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit, offset, ok := parseLimitOffset(w, r, defaultAdminListLimit, maxAdminListLimit)
+	if !ok {
+		return
+	}
+
+	reports, err := reportRepo.FindByStatus(status, limit, offset)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	audit(r, auditListReports, auditTargetReport, 0, "status="+string(status))
+
+	resp := make([]AdminReportResponse, 0, len(reports))
+	for i := range reports {
+		resp = append(resp, newAdminReportResponse(&reports[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// adminReviewReportHandler godoc
+// @Summary Close a report
+// @Description Mark an open report resolved or dismissed. Moderator only.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Report ID"
+// @Param review body ReviewReportRequest true "Outcome"
+// @Success 200 {object} AdminReportResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/reports/{id}/review [post]
+func adminReviewReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reportID, ok := pathID(w, r, "id", "report")
+	if !ok {
+		return
+	}
+
+	var req ReviewReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.Status != models.ReportResolved && req.Status != models.ReportDismissed {
+		http.Error(w, "Status must be \"resolved\" or \"dismissed\"", http.StatusBadRequest)
+		return
+	}
+
+	report, err := reportRepo.Review(reportID, currentUserID(r), req.Status)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Open report not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to update report", http.StatusInternalServerError)
+		return
+	}
+	audit(r, auditReviewReport, auditTargetReport, report.ID,
+		strings.TrimSpace(string(req.Status)+" "+strings.TrimSpace(req.Note)))
+	notifyReportReviewed(report)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newAdminReportResponse(report))
+}
+
+// adminReportMessagesHandler godoc
+// @Summary View a reported conversation
+// @Description Get every message between the reporter and the reported user, including messages hidden from them. Moderator only.
+// @Tags admin
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Report ID"
+// @Success 200 {array} AdminMessageResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/reports/{id}/messages [get]
+func adminReportMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reportID, ok := pathID(w, r, "id", "report")
+	if !ok {
+		return
+	}
+
+	report, err := reportRepo.FindByID(reportID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Report not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	writeAdminConversation(w, r, report.ReporterID, report.ReportedID, auditViewReportMessages, auditTargetReport, report.ID)
+}
+
+// adminMatchMessagesHandler godoc
+// @Summary View a match's conversation
+// @Description Get every message between the two users of a match, including messages hidden from them after an unmatch or block. Moderator only.
+// @Tags admin
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Match ID"
+// @Success 200 {array} AdminMessageResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/matches/{id}/messages [get]
+func adminMatchMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	matchID, ok := pathID(w, r, "id", "match")
+	if !ok {
+		return
+	}
+
+	match, err := matchRepo.FindByID(matchID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Match not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	writeAdminConversation(w, r, match.User1ID, match.User2ID, auditViewMatchMessages, auditTargetMatch, match.ID)
+}
+
+// writeAdminConversation writes every message between two users and audits
+// the access against the report or match it was made through
+func writeAdminConversation(w http.ResponseWriter, r *http.Request, user1ID, user2ID uint, action, targetType string, targetID uint) {
+	messages, err := messageRepo.GetConversationIncludingHidden(user1ID, user2ID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	audit(r, action, targetType, targetID, "")
+
+	resp := make([]AdminMessageResponse, 0, len(messages))
+	for i := range messages {
+		resp = append(resp, AdminMessageResponse{
+			MessageResponse: newMessageResponse(&messages[i]),
+			HiddenAt:        messages[i].HiddenAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// adminUserHandler godoc
+// @Summary View a user
+// @Description Get a user's account and full profile. Moderator only.
+// @Tags admin
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} AdminUserResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id} [get]
+func adminUserHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := findAdminTarget(w, r)
+	if !ok {
+		return
+	}
+
+	resp := newAdminUserResponse(user)
+	profile, err := profileRepo.FindByUserID(user.ID)
+	switch {
+	case err == nil:
+		profileResp := newOwnProfileResponse(profile)
+		resp.Profile = &profileResp
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	audit(r, auditViewUser, auditTargetUser, user.ID, "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// adminSuspendUserHandler godoc
+// @Summary Suspend a user
+// @Description Deactivate a user's account and sign them out everywhere. Moderators can't suspend moderators or admins. Moderator only.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Param suspension body SuspendUserRequest false "Reason"
+// @Success 200 {object} AdminUserResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/suspend [post]
+func adminSuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SuspendUserRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+	}
+
+	user, ok := findAdminTarget(w, r)
+	if !ok {
+		return
+	}
+	if user.ID == currentUserID(r) {
+		http.Error(w, "You can't suspend yourself", http.StatusBadRequest)
+		return
+	}
+	if actor := currentRole(r); actor != models.RoleAdmin && user.Role.AtLeast(actor) {
+		http.Error(w, "Only admins can suspend moderators and admins", http.StatusForbidden)
+		return
+	}
+
+	if err := setUserActive(user.ID, false); err != nil {
+		http.Error(w, "Failed to suspend user", http.StatusInternalServerError)
+		return
+	}
+	user.IsActive = false
+	user.DeactivatedAt = nil
+	audit(r, auditSuspendUser, auditTargetUser, user.ID, strings.TrimSpace(req.Reason))
+	notifyAccountSuspended(user.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newAdminUserResponse(user))
+}
+
+// adminReactivateUserHandler godoc
+// @Summary Reactivate a user
+// @Description Lift a user's suspension. They have to log in again. Accounts their owner deactivated or that are due for deletion can't be reactivated this way. Moderators can't reactivate moderators or admins. Moderator only.
+// @Tags admin
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} AdminUserResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/reactivate [post]
+func adminReactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := findAdminTarget(w, r)
+	if !ok {
+		return
+	}
+	if actor := currentRole(r); actor != models.RoleAdmin && user.Role.AtLeast(actor) {
+		http.Error(w, "Only admins can reactivate moderators and admins", http.StatusForbidden)
+		return
+	}
+
+	if err := setUserActive(user.ID, true); err != nil {
+		if errors.Is(err, repositories.ErrNotSuspended) {
+			http.Error(w, "User isn't suspended", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to reactivate user", http.StatusInternalServerError)
+		return
+	}
+	user.IsActive = true
+	audit(r, auditReactivateUser, auditTargetUser, user.ID, "")
+	notifyAccountReactivated(user.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newAdminUserResponse(user))
+}
+
+// adminSetRoleHandler godoc
+// @Summary Change a user's role
+// @Description Make a user a regular user, moderator or admin. The change applies when their access token is next refreshed. Admins can't change their own role. Admin only.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Param role body SetRoleRequest true "New role"
+// @Success 200 {object} AdminUserResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/role [put]
+func adminSetRoleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if !req.Role.Valid() {
+		http.Error(w, "Invalid role", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := findAdminTarget(w, r)
+	if !ok {
+		return
+	}
+	// Keeps the last admin from locking everyone out by accident
+	if user.ID == currentUserID(r) {
+		http.Error(w, "You can't change your own role", http.StatusBadRequest)
+		return
+	}
+
+	from := user.Role
+	if err := userRepo.SetRole(user.ID, req.Role); err != nil {
+		http.Error(w, "Failed to change role", http.StatusInternalServerError)
+		return
+	}
+	user.Role = req.Role
+	audit(r, auditSetRole, auditTargetUser, user.ID, fmt.Sprintf("%s -> %s", from, req.Role))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newAdminUserResponse(user))
+}
+
+// findAdminTarget loads the user in the path, writing the error response
+// and returning false on failure
+func findAdminTarget(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	userID, ok := pathID(w, r, "id", "user")
+	if !ok {
+		return nil, false
+	}
+
+	user, err := userRepo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return user, true
+}
+
+// adminAuditLogHandler godoc
+// @Summary View the audit log
+// @Description Get admin API actions, newest first. Admin only.
+// @Tags admin
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param limit query int false "Maximum number of entries to return (default 50, max 200)"
+// @Param offset query int false "Number of entries to skip"
+// @Success 200 {array} AuditLogResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/audit-log [get]
+func adminAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, offset, ok := parseLimitOffset(w, r, defaultAdminListLimit, maxAdminListLimit)
+	if !ok {
+		return
+	}
+
+	entries, err := auditLogRepo.List(limit, offset)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]AuditLogResponse, 0, len(entries))
+	for _, entry := range entries {
+		resp = append(resp, AuditLogResponse{
+			ID:         entry.ID,
+			ActorID:    entry.ActorID,
+			Action:     entry.Action,
+			TargetType: entry.TargetType,
+			TargetID:   entry.TargetID,
+			Details:    entry.Details,
+			CreatedAt:  entry.CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/connectplus/models"
+	"gorm.io/gorm"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// RefreshTokenRequest represents the request payload for refreshing an access token
+// @swagger:model
+type RefreshTokenRequest struct {
+	// Refresh token from login, signup or a previous refresh
+	// required: true
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TokenResponse represents a freshly issued access and refresh token pair
+// @swagger:model
+type TokenResponse struct {
+	// JWT access token
+	// example: eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9...
+	Token string `json:"token"`
+
+	// Replacement refresh token; the one sent in the request is no longer valid
+	RefreshToken string `json:"refresh_token"`
+
+	// Access token lifetime in seconds
+	// example: 900
+	ExpiresIn int `json:"expires_in"`
+}
+
+// newRefreshToken returns a random refresh token and the hash stored for it
+func newRefreshToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+// hashToken returns the hex SHA-256 of an opaque token. Refresh tokens are
+// random, so an unsalted fast hash is enough to keep them out of the database.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueSession starts a new session for the user and returns its access and
+// refresh tokens
+func issueSession(user *models.User, r *http.Request) (accessToken, refreshToken string, err error) {
+	refreshToken, hash, err := newRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	session := &models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: hash,
+		UserAgent:        userAgent,
+		ExpiresAt:        time.Now().Add(refreshTokenTTL),
+	}
+	if err := sessionRepo.Create(session); err != nil {
+		return "", "", err
+	}
+
+	accessToken, err = generateToken(int(user.ID), user.Role, session.ID)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+// refreshTokenHandler godoc
+// @Summary Refresh access token
+// @Description Exchange a refresh token for a new access token. The refresh token is rotated; presenting an already-rotated token revokes the session.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param refresh body RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/refresh [post]
+func refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	oldHash := hashToken(req.RefreshToken)
+	session, err := sessionRepo.FindByRefreshTokenHash(oldHash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// A rotated-out token being replayed means it was copied; revoke the
+		// session so neither holder can keep using it
+		if reused, err := sessionRepo.FindByPreviousTokenHash(oldHash); err == nil {
+			log.Printf("Refresh token reuse detected for session %d, revoking", reused.ID)
+			if err := sessionRepo.Revoke(reused.ID); err != nil {
+				log.Printf("Failed to revoke session %d: %v", reused.ID, err)
+			}
+		}
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !session.IsActive(time.Now()) {
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	refreshToken, newHash, err := newRefreshToken()
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	err = sessionRepo.Rotate(session.ID, oldHash, newHash, time.Now().Add(refreshTokenTTL))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Lost a race with a concurrent refresh of the same token
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	// Read the role afresh so role changes apply from the next refresh
+	user, err := userRepo.FindByID(session.UserID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !user.IsActive {
+		http.Error(w, "Account is inactive", http.StatusForbidden)
+		return
+	}
+
+	token, err := generateToken(int(user.ID), user.Role, session.ID)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
+	})
+}
+
+// logoutHandler godoc
+// @Summary Log out
+// @Description Revoke the session of the access token used for this request
+// @Tags auth
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/logout [post]
+func logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := sessionRepo.Revoke(currentSessionID(r)); err != nil {
+		http.Error(w, "Failed to log out", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Logged out successfully",
+	})
+}
+
+// logoutAllHandler godoc
+// @Summary Log out everywhere
+// @Description Revoke every session of the authenticated user, including the current one
+// @Tags auth
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/logout-all [post]
+func logoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := sessionRepo.RevokeAllForUser(currentUserID(r)); err != nil {
+		http.Error(w, "Failed to log out", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Logged out of all sessions",
+	})
+}
@@ -0,0 +1,75 @@
+// Package cache holds small in-process caches for values that may be
+// slightly stale, such as per-user flags checked on every request.
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// TTL maps keys to values that expire a fixed time after they are set.
+// Expired entries are dropped when they are next looked up or when the
+// cache is swept during Set.
+type TTL[K comparable, V any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[K]entry[V]
+	// sweepAt is when Set next drops every expired entry, so keys that are
+	// never looked up again don't pile up
+	sweepAt time.Time
+	now     func() time.Time
+}
+
+// NewTTL returns an empty cache whose entries live for ttl. A ttl of zero
+// or less disables caching: Get never finds anything.
+func NewTTL[K comparable, V any](ttl time.Duration) *TTL[K, V] {
+	return &TTL[K, V]{ttl: ttl, entries: make(map[K]entry[V]), now: time.Now}
+}
+
+// Get returns the value for key if it is set and has not expired.
+func (c *TTL[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	if !c.now().Before(e.expiresAt) {
+		delete(c.entries, key)
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Set stores value for key until the ttl passes.
+func (c *TTL[K, V]) Set(key K, value V) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if !now.Before(c.sweepAt) {
+		for k, e := range c.entries {
+			if !now.Before(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		c.sweepAt = now.Add(c.ttl)
+	}
+	c.entries[key] = entry[V]{value: value, expiresAt: now.Add(c.ttl)}
+}
+
+// Delete forgets key, so the next Get misses.
+func (c *TTL[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock lets tests move time forward by hand.
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) now() time.Time { return f.t }
+
+func newTestCache(ttl time.Duration) (*TTL[uint, bool], *fakeClock) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c := NewTTL[uint, bool](ttl)
+	c.now = clock.now
+	return c, clock
+}
+
+func TestGetReturnsValueUntilExpiry(t *testing.T) {
+	c, clock := newTestCache(time.Minute)
+	c.Set(1, true)
+
+	if v, ok := c.Get(1); !ok || !v {
+		t.Fatalf("Get(1) = %v, %v; want true, true", v, ok)
+	}
+
+	clock.t = clock.t.Add(time.Minute)
+	if _, ok := c.Get(1); ok {
+		t.Fatal("entry still present after ttl")
+	}
+}
+
+func TestGetMissingKey(t *testing.T) {
+	c, _ := newTestCache(time.Minute)
+	if _, ok := c.Get(42); ok {
+		t.Fatal("found a key that was never set")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	c, _ := newTestCache(time.Minute)
+	c.Set(1, true)
+	c.Delete(1)
+	if _, ok := c.Get(1); ok {
+		t.Fatal("entry still present after Delete")
+	}
+}
+
+func TestSetSweepsExpiredEntries(t *testing.T) {
+	c, clock := newTestCache(time.Minute)
+	c.Set(1, true)
+	c.Set(2, true)
+
+	clock.t = clock.t.Add(2 * time.Minute)
+	c.Set(3, true)
+	if len(c.entries) != 1 {
+		t.Fatalf("%d entries after sweep, want 1", len(c.entries))
+	}
+}
+
+func TestZeroTTLDisablesCaching(t *testing.T) {
+	c, _ := newTestCache(0)
+	c.Set(1, true)
+	if _, ok := c.Get(1); ok {
+		t.Fatal("found an entry with caching disabled")
+	}
+}
@@ -0,0 +1,116 @@
+// Package chat fans real-time events out to the open connections of each
+// user within this process.
+package chat
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// sendBuffer is how many events may queue for a connection before it is
+// considered too slow and dropped.
+const sendBuffer = 32
+
+// Client is one open connection of a user. Events queued for it are read
+// from Events until the hub closes the channel.
+type Client struct {
+	UserID uint
+	send   chan []byte
+}
+
+// Events returns the encoded events queued for the connection. The channel
+// is closed when the client is unregistered or falls behind.
+func (c *Client) Events() <-chan []byte {
+	return c.send
+}
+
+// Hub tracks the open connections of every user.
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[uint]map[*Client]struct{}
+}
+
+// NewHub returns an empty hub.
+func NewHub() *Hub {
+	return &Hub{clients: make(map[uint]map[*Client]struct{})}
+}
+
+// Register adds a connection for the user.
+func (h *Hub) Register(userID uint) *Client {
+	c := &Client{UserID: userID, send: make(chan []byte, sendBuffer)}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.clients[userID] == nil {
+		h.clients[userID] = make(map[*Client]struct{})
+	}
+	h.clients[userID][c] = struct{}{}
+	return c
+}
+
+// Unregister removes a connection and closes its event channel. It is safe
+// to call more than once.
+func (h *Hub) Unregister(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(c)
+}
+
+// Disconnect unregisters every connection of the user, returning how many
+// were open. Closing their event channels ends the connections.
+func (h *Hub) Disconnect(userID uint) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for c := range h.clients[userID] {
+		h.remove(c)
+		n++
+	}
+	return n
+}
+
+// remove must be called with h.mu held for writing.
+func (h *Hub) remove(c *Client) {
+	conns, ok := h.clients[c.UserID]
+	if !ok {
+		return
+	}
+	if _, ok := conns[c]; !ok {
+		return
+	}
+	delete(conns, c)
+	close(c.send)
+	if len(conns) == 0 {
+		delete(h.clients, c.UserID)
+	}
+}
+
+// Send encodes event as JSON and queues it for every connection of the
+// user, returning how many connections it was queued for. Connections whose
+// buffer is full are dropped rather than blocking the sender.
+func (h *Hub) Send(userID uint, event any) (int, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delivered := 0
+	for c := range h.clients[userID] {
+		select {
+		case c.send <- data:
+			delivered++
+		default:
+			h.remove(c)
+		}
+	}
+	return delivered, nil
+}
+
+// Online reports whether the user has at least one open connection.
+func (h *Hub) Online(userID uint) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients[userID]) > 0
+}
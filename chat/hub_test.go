@@ -0,0 +1,119 @@
+package chat
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type testEvent struct {
+	Type string `json:"type"`
+}
+
+func receive(t *testing.T, c *Client) testEvent {
+	t.Helper()
+	select {
+	case data, ok := <-c.Events():
+		if !ok {
+			t.Fatal("event channel closed")
+		}
+		var e testEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	default:
+		t.Fatal("no event queued")
+	}
+	return testEvent{}
+}
+
+func TestSendReachesEveryConnectionOfUser(t *testing.T) {
+	h := NewHub()
+	phone := h.Register(1)
+	laptop := h.Register(1)
+	other := h.Register(2)
+
+	n, err := h.Send(1, testEvent{Type: "message"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("delivered to %d connections, want 2", n)
+	}
+	if e := receive(t, phone); e.Type != "message" {
+		t.Errorf("phone got %q", e.Type)
+	}
+	if e := receive(t, laptop); e.Type != "message" {
+		t.Errorf("laptop got %q", e.Type)
+	}
+	if len(other.Events()) != 0 {
+		t.Error("event leaked to another user")
+	}
+}
+
+func TestSendToOfflineUser(t *testing.T) {
+	h := NewHub()
+	n, err := h.Send(1, testEvent{Type: "message"})
+	if err != nil || n != 0 {
+		t.Fatalf("Send = %d, %v; want 0, nil", n, err)
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	h := NewHub()
+	c := h.Register(1)
+	if !h.Online(1) {
+		t.Fatal("user should be online")
+	}
+
+	h.Unregister(c)
+	h.Unregister(c)
+	if h.Online(1) {
+		t.Error("user should be offline")
+	}
+	if _, ok := <-c.Events(); ok {
+		t.Error("event channel should be closed")
+	}
+}
+
+func TestSlowClientIsDropped(t *testing.T) {
+	h := NewHub()
+	slow := h.Register(1)
+	for i := 0; i < sendBuffer; i++ {
+		if n, _ := h.Send(1, testEvent{Type: "typing"}); n != 1 {
+			t.Fatalf("event %d delivered to %d connections", i, n)
+		}
+	}
+
+	if n, _ := h.Send(1, testEvent{Type: "typing"}); n != 0 {
+		t.Errorf("full client got event")
+	}
+	if h.Online(1) {
+		t.Error("slow client should have been dropped")
+	}
+	for range slow.Events() {
+	}
+}
+
+func TestDisconnect(t *testing.T) {
+	h := NewHub()
+	a := h.Register(1)
+	b := h.Register(1)
+	other := h.Register(2)
+
+	if n := h.Disconnect(1); n != 2 {
+		t.Fatalf("Disconnect = %d, want 2", n)
+	}
+	for _, c := range []*Client{a, b} {
+		if _, ok := <-c.Events(); ok {
+			t.Fatal("event channel still open")
+		}
+	}
+	if h.Online(1) {
+		t.Fatal("user still online")
+	}
+	if !h.Online(2) {
+		t.Fatal("other user was disconnected")
+	}
+	h.Unregister(other)
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/connectplus/chat"
+	"github.com/connectplus/models"
+	"golang.org/x/net/websocket"
+	"gorm.io/gorm"
+)
+
+const (
+	maxMessageLength = 2000
+	// maxChatFrameBytes bounds a single incoming frame; the longest message
+	// is 2000 characters of up to 4 bytes each plus the JSON envelope
+	maxChatFrameBytes = 16 << 10
+)
+
+// Chat event types. "message", "typing" and "read" are sent by clients and
+// relayed by the server; "error" is only sent by the server.
+const (
+	chatEventMessage = "message"
+	chatEventTyping  = "typing"
+	chatEventRead    = "read"
+	chatEventError   = "error"
+)
+
+var chatHub = chat.NewHub()
+
+// MessageResponse represents a stored chat message
+// @swagger:model
+type MessageResponse struct {
+	ID         uint      `json:"id"`
+	SenderID   uint      `json:"sender_id"`
+	ReceiverID uint      `json:"receiver_id"`
+	Content    string    `json:"content"`
+	IsRead     bool      `json:"is_read"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func newMessageResponse(message *models.Message) MessageResponse {
+	return MessageResponse{
+		ID:         message.ID,
+		SenderID:   message.SenderID,
+		ReceiverID: message.ReceiverID,
+		Content:    message.Content,
+		IsRead:     message.IsRead,
+		CreatedAt:  message.CreatedAt,
+	}
+}
+
+// ChatEvent is a single frame on the chat WebSocket, in either direction
+// @swagger:model
+type ChatEvent struct {
+	// One of "message", "typing", "read" or "error"
+	// required: true
+	Type string `json:"type"`
+
+	// Recipient of a message or typing event sent by the client
+	To uint `json:"to,omitempty"`
+
+	// User who is typing or who read a message, set by the server
+	From uint `json:"from,omitempty"`
+
+	// Text of a message sent by the client
+	Content string `json:"content,omitempty"`
+
+	// Opaque value chosen by the client for a message, echoed back on the
+	// stored message or error so the client can match them up
+	ClientID string `json:"client_id,omitempty"`
+
+	// Message a read receipt refers to
+	MessageID uint `json:"message_id,omitempty"`
+
+	// Stored message, set by the server on message events
+	Message *MessageResponse `json:"message,omitempty"`
+
+	// HTTP status equivalent of an error, such as 403 when the users are
+	// not matched
+	Code int `json:"code,omitempty"`
+
+	// Reason a client event was rejected
+	Error string `json:"error,omitempty"`
+}
+
+// chatHandler godoc
+// @Summary Open chat connection
+// @Description Upgrade to a WebSocket carrying JSON ChatEvent frames. Send {"type":"message","to":2,"content":"Hi","client_id":"abc"} to send a message to an accepted match; it is stored and delivered as a "message" event to the recipient and to all of the sender's connections. Send {"type":"typing","to":2} to show a typing indicator and {"type":"read","message_id":7} to mark a received message read, which notifies its sender. Rejected events are answered with an "error" event on the same connection whose code is the matching HTTP status, 403 if the users are not matched.
+// @Tags messages
+// @Security ApiKeyAuth
+// @Success 101 {object} ChatEvent
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /ws/chat [get]
+func chatHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+	// The default websocket.Handler rejects clients that send no Origin
+	// header, which includes the mobile app. Origin checks guard against
+	// cookie-authenticated cross-site requests; this endpoint authenticates
+	// with a bearer token, so any origin is accepted.
+	server := websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			serveChat(ws, userID)
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+// serveChat relays events between one connection and the hub until either
+// side closes
+func serveChat(ws *websocket.Conn, userID uint) {
+	ws.MaxPayloadBytes = maxChatFrameBytes
+	client := chatHub.Register(userID)
+	defer chatHub.Unregister(client)
+	presenceStore.Connect(userID)
+	defer presenceStore.Disconnect(userID)
+
+	go func() {
+		for data := range client.Events() {
+			if err := websocket.Message.Send(ws, string(data)); err != nil {
+				break
+			}
+		}
+		// Unblocks the read loop when the hub drops a slow connection
+		ws.Close()
+	}()
+
+	for {
+		var frame []byte
+		if err := websocket.Message.Receive(ws, &frame); err != nil {
+			if errors.Is(err, websocket.ErrFrameTooLarge) {
+				sendChatError(ws, "", &chatError{http.StatusRequestEntityTooLarge, "Event too large"})
+				continue
+			}
+			return
+		}
+		var event ChatEvent
+		if err := json.Unmarshal(frame, &event); err != nil {
+			sendChatError(ws, "", &chatError{http.StatusBadRequest, "Invalid event"})
+			continue
+		}
+
+		var chatErr *chatError
+		switch event.Type {
+		case chatEventMessage:
+			_, chatErr = sendMessage(userID, event.To, event.Content, event.ClientID)
+		case chatEventTyping:
+			chatErr = handleChatTyping(userID, &event)
+		case chatEventRead:
+			chatErr = handleChatRead(userID, &event)
+		default:
+			chatErr = &chatError{http.StatusBadRequest, "Unknown event type"}
+		}
+		if chatErr != nil {
+			sendChatError(ws, event.ClientID, chatErr)
+		}
+	}
+}
+
+// chatError is a rejected chat action. Code is the HTTP status the same
+// failure gets from the REST endpoints.
+type chatError struct {
+	Code    int
+	Message string
+}
+
+func sendChatError(ws *websocket.Conn, clientID string, chatErr *chatError) {
+	websocket.JSON.Send(ws, ChatEvent{Type: chatEventError, ClientID: clientID, Code: chatErr.Code, Error: chatErr.Message})
+}
+
+// requireAcceptedMatch rejects chat between users who aren't currently
+// matched, including after a match is declined or removed
+func requireAcceptedMatch(userID, otherID uint) *chatError {
+	if otherID == 0 || otherID == userID {
+		return &chatError{http.StatusBadRequest, "Invalid recipient"}
+	}
+	match, err := matchRepo.FindByUsers(userID, otherID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return &chatError{http.StatusInternalServerError, "Database error"}
+	}
+	if err != nil || match.Status != models.MatchAccepted {
+		return &chatError{http.StatusForbidden, "Messaging requires an accepted match"}
+	}
+	return nil
+}
+
+// sendMessage stores a message and pushes it to both participants' open
+// connections. clientID is echoed to the sender's connections only.
+func sendMessage(senderID, receiverID uint, content, clientID string) (*models.Message, *chatError) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, &chatError{http.StatusBadRequest, "Message content is required"}
+	}
+	if utf8.RuneCountInString(content) > maxMessageLength {
+		return nil, &chatError{http.StatusBadRequest, "Message must be at most 2000 characters"}
+	}
+	if chatErr := requireAcceptedMatch(senderID, receiverID); chatErr != nil {
+		return nil, chatErr
+	}
+
+	message := &models.Message{
+		SenderID:   senderID,
+		ReceiverID: receiverID,
+		Content:    content,
+	}
+	if err := messageRepo.Create(message); err != nil {
+		return nil, &chatError{http.StatusInternalServerError, "Failed to send message"}
+	}
+
+	resp := newMessageResponse(message)
+	pushChatEvent(receiverID, ChatEvent{Type: chatEventMessage, Message: &resp})
+	pushChatEvent(senderID, ChatEvent{Type: chatEventMessage, Message: &resp, ClientID: clientID})
+	notifyNewMessage(message)
+	return message, nil
+}
+
+// handleChatTyping forwards a typing indicator; these are not stored
+func handleChatTyping(userID uint, event *ChatEvent) *chatError {
+	if chatErr := requireAcceptedMatch(userID, event.To); chatErr != nil {
+		return chatErr
+	}
+	pushChatEvent(event.To, ChatEvent{Type: chatEventTyping, From: userID})
+	return nil
+}
+
+// handleChatRead marks a message received by the user as read and sends the
+// receipt to its sender and the user's other connections
+func handleChatRead(userID uint, event *ChatEvent) *chatError {
+	message, err := messageRepo.FindByID(event.MessageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &chatError{http.StatusNotFound, "Message not found"}
+		}
+		return &chatError{http.StatusInternalServerError, "Database error"}
+	}
+	// Senders can't mark their own messages read, and hidden conversations
+	// are gone as far as the participants are concerned
+	if message.ReceiverID != userID || message.HiddenAt != nil {
+		return &chatError{http.StatusNotFound, "Message not found"}
+	}
+	if message.IsRead {
+		return nil
+	}
+	if err := messageRepo.MarkAsRead(message.ID); err != nil {
+		return &chatError{http.StatusInternalServerError, "Failed to mark message read"}
+	}
+
+	receipt := ChatEvent{Type: chatEventRead, From: userID, MessageID: message.ID}
+	pushChatEvent(message.SenderID, receipt)
+	pushChatEvent(userID, receipt)
+	return nil
+}
+
+func pushChatEvent(userID uint, event ChatEvent) {
+	if _, err := chatHub.Send(userID, event); err != nil {
+		log.Printf("Failed to push chat event to user %d: %v", userID, err)
+	}
+}
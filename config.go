@@ -0,0 +1,290 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/connectplus/mailer"
+	"github.com/connectplus/notify"
+	"github.com/connectplus/storage"
+)
+
+// Config holds settings read from the environment at startup
+type Config struct {
+	// BaseURL is the public URL of the API, used to build links in emails
+	BaseURL string
+
+	// WebAppURL is the URL of the client app, used for links that open a
+	// page rather than an API endpoint, such as password reset
+	WebAppURL string
+
+	// RequireVerifiedEmail blocks unverified users from discovery and messaging
+	RequireVerifiedEmail bool
+
+	// RematchCooldown keeps a pair who unmatched out of each other's
+	// discovery feed, and stops them matching again, for this long
+	RematchCooldown time.Duration
+
+	// MatchExpiry expires accepted matches where nobody sends a message
+	// within this long; zero disables expiry. The sweep runs every
+	// MatchExpirySweepInterval.
+	MatchExpiry              time.Duration
+	MatchExpirySweepInterval time.Duration
+
+	// AccountDeletionGrace is how long a deleted account can still be
+	// restored by logging in; AccountPurgeInterval is how often accounts
+	// past their grace period, and expired data exports, are purged
+	AccountDeletionGrace time.Duration
+	AccountPurgeInterval time.Duration
+
+	// DataExportTTL is how long a finished data export can be downloaded
+	DataExportTTL time.Duration
+
+	// ActiveUserCacheTTL is how long authMiddleware trusts its cached copy
+	// of whether a user's account is active. Suspensions made on this
+	// instance apply at once; others apply within this long.
+	ActiveUserCacheTTL time.Duration
+
+	// PresenceOnlineWindow is how long a user counts as online after their
+	// last request when they have no chat connection open.
+	// PresenceFlushInterval is how often last-active times are saved.
+	PresenceOnlineWindow  time.Duration
+	PresenceFlushInterval time.Duration
+
+	// NotificationBatchWindow is how long further notifications in a burst,
+	// such as several messages from one sender, are held and combined
+	// after the first is sent. Zero sends each one.
+	NotificationBatchWindow time.Duration
+
+	// AdminUserIDs are given the admin role at startup, so a new deployment
+	// has someone who can grant roles
+	AdminUserIDs []uint
+
+	// Mailer selects how email is delivered: "smtp", "file" or "log"
+	Mailer       string
+	MailDir      string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+
+	// Storage selects where uploaded photos are kept: "local" or "s3"
+	Storage        string
+	StorageDir     string
+	StorageBaseURL string
+	S3Endpoint     string
+	S3Region       string
+	S3Bucket       string
+	S3AccessKey    string
+	S3SecretKey    string
+	S3PublicURL    string
+
+	// FCMCredentialsFile is a Google service account key for the Firebase
+	// project, used to push to Android and the web. Those pushes are off
+	// without it.
+	FCMCredentialsFile string
+	// APNsKeyFile is the .p8 auth key used to push to iOS, with its key ID,
+	// the Apple team ID and the app's bundle ID as topic. iOS pushes are
+	// off without it.
+	APNsKeyFile  string
+	APNsKeyID    string
+	APNsTeamID   string
+	APNsTopic    string
+	APNsEndpoint string
+}
+
+var cfg Config
+
+func loadConfig() Config {
+	baseURL := envString("APP_BASE_URL", "http://localhost:8080")
+	return Config{
+		BaseURL:                  baseURL,
+		WebAppURL:                envString("WEB_APP_URL", "http://localhost:8080"),
+		RequireVerifiedEmail:     envBool("REQUIRE_VERIFIED_EMAIL", false),
+		RematchCooldown:          envDuration("REMATCH_COOLDOWN", 30*24*time.Hour),
+		AccountDeletionGrace:     envDuration("ACCOUNT_DELETION_GRACE", 30*24*time.Hour),
+		AccountPurgeInterval:     envDuration("ACCOUNT_PURGE_INTERVAL", time.Hour),
+		DataExportTTL:            envDuration("DATA_EXPORT_TTL", 7*24*time.Hour),
+		ActiveUserCacheTTL:       envDuration("ACTIVE_USER_CACHE_TTL", 30*time.Second),
+		PresenceOnlineWindow:     envDuration("PRESENCE_ONLINE_WINDOW", 5*time.Minute),
+		PresenceFlushInterval:    envDuration("PRESENCE_FLUSH_INTERVAL", time.Minute),
+		NotificationBatchWindow:  envDuration("NOTIFICATION_BATCH_WINDOW", 2*time.Minute),
+		AdminUserIDs:             envUintList("ADMIN_USER_IDS"),
+		MatchExpiry:              envDuration("MATCH_EXPIRY", 0),
+		MatchExpirySweepInterval: envDuration("MATCH_EXPIRY_SWEEP_INTERVAL", 10*time.Minute),
+		Mailer:                   envString("MAILER", "log"),
+		MailDir:                  envString("MAIL_DIR", "tmp/mail"),
+		SMTPHost:                 envString("SMTP_HOST", "localhost"),
+		SMTPPort:                 envInt("SMTP_PORT", 587),
+		SMTPUsername:             os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:             os.Getenv("SMTP_PASSWORD"),
+		MailFrom:                 envString("MAIL_FROM", "Connect+ <noreply@connectplus.com>"),
+		Storage:                  envString("STORAGE", "local"),
+		StorageDir:               envString("STORAGE_DIR", "uploads"),
+		StorageBaseURL:           envString("STORAGE_BASE_URL", baseURL+"/uploads"),
+		S3Endpoint:               envString("S3_ENDPOINT", "https://s3.amazonaws.com"),
+		S3Region:                 envString("S3_REGION", "us-east-1"),
+		S3Bucket:                 os.Getenv("S3_BUCKET"),
+		S3AccessKey:              os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:              os.Getenv("S3_SECRET_KEY"),
+		S3PublicURL:              os.Getenv("S3_PUBLIC_URL"),
+		FCMCredentialsFile:       os.Getenv("FCM_CREDENTIALS_FILE"),
+		APNsKeyFile:              os.Getenv("APNS_KEY_FILE"),
+		APNsKeyID:                os.Getenv("APNS_KEY_ID"),
+		APNsTeamID:               os.Getenv("APNS_TEAM_ID"),
+		APNsTopic:                os.Getenv("APNS_TOPIC"),
+		APNsEndpoint:             envString("APNS_ENDPOINT", "https://api.push.apple.com"),
+	}
+}
+
+// newMailer returns the mailer selected by the config
+func newMailer(c Config) mailer.Mailer {
+	switch c.Mailer {
+	case "smtp":
+		return &mailer.SMTPMailer{
+			Host:     c.SMTPHost,
+			Port:     c.SMTPPort,
+			Username: c.SMTPUsername,
+			Password: c.SMTPPassword,
+			From:     c.MailFrom,
+		}
+	case "file":
+		return &mailer.FileMailer{Dir: c.MailDir}
+	case "log":
+		return &mailer.LogMailer{}
+	default:
+		log.Printf("Unknown MAILER %q, logging mail instead", c.Mailer)
+		return &mailer.LogMailer{}
+	}
+}
+
+// newBlobStore returns the blob store selected by the config
+func newBlobStore(c Config) storage.BlobStore {
+	switch c.Storage {
+	case "s3":
+		return &storage.S3Store{
+			Endpoint:  c.S3Endpoint,
+			Region:    c.S3Region,
+			Bucket:    c.S3Bucket,
+			AccessKey: c.S3AccessKey,
+			SecretKey: c.S3SecretKey,
+			PublicURL: c.S3PublicURL,
+		}
+	case "local":
+		return &storage.LocalStore{Dir: c.StorageDir, BaseURL: c.StorageBaseURL}
+	default:
+		log.Printf("Unknown STORAGE %q, storing uploads locally", c.Storage)
+		return &storage.LocalStore{Dir: c.StorageDir, BaseURL: c.StorageBaseURL}
+	}
+}
+
+// newPushers returns a pusher for each platform whose push provider is
+// configured
+func newPushers(c Config) map[notify.Platform]notify.Pusher {
+	pushers := make(map[notify.Platform]notify.Pusher)
+
+	if c.FCMCredentialsFile != "" {
+		credentials, err := os.ReadFile(c.FCMCredentialsFile)
+		if err == nil {
+			var account *notify.GoogleServiceAccount
+			if account, err = notify.ParseGoogleServiceAccount(credentials); err == nil {
+				fcm := &notify.FCMPusher{ProjectID: account.ProjectID, AccessToken: account.AccessToken}
+				pushers[notify.PlatformAndroid] = fcm
+				pushers[notify.PlatformWeb] = fcm
+			}
+		}
+		if err != nil {
+			log.Printf("Failed to load FCM_CREDENTIALS_FILE, not pushing to Android or web: %v", err)
+		}
+	}
+
+	if c.APNsKeyFile != "" {
+		pem, err := os.ReadFile(c.APNsKeyFile)
+		if err == nil {
+			var key *ecdsa.PrivateKey
+			if key, err = notify.ParseAPNsKey(pem); err == nil {
+				pushers[notify.PlatformIOS] = &notify.APNsPusher{
+					Endpoint: c.APNsEndpoint,
+					Topic:    c.APNsTopic,
+					KeyID:    c.APNsKeyID,
+					TeamID:   c.APNsTeamID,
+					Key:      key,
+				}
+			}
+		}
+		if err != nil {
+			log.Printf("Failed to load APNS_KEY_FILE, not pushing to iOS: %v", err)
+		}
+	}
+
+	return pushers
+}
+
+func envString(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %d", key, v, fallback)
+		return fallback
+	}
+	return n
+}
+
+func envBool(key string, fallback bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %t", key, v, fallback)
+		return fallback
+	}
+	return b
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %s", key, v, fallback)
+		return fallback
+	}
+	return d
+}
+
+// envUintList reads a comma-separated list of IDs, skipping invalid entries
+func envUintList(key string) []uint {
+	var ids []uint
+	for _, field := range strings.Split(os.Getenv(key), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			log.Printf("Invalid ID %q in %s, skipping", field, key)
+			continue
+		}
+		ids = append(ids, uint(n))
+	}
+	return ids
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/connectplus/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// apnsTokenPattern matches APNs device tokens, which are hex. Apple
+	// says not to rely on their length, which is 32 bytes today.
+	apnsTokenPattern = regexp.MustCompile(`^[0-9a-f]{64,400}$`)
+	// fcmTokenPattern matches Firebase Cloud Messaging registration tokens
+	fcmTokenPattern = regexp.MustCompile(`^[A-Za-z0-9_:\-]{32,512}$`)
+)
+
+const maxAppVersionLength = 50
+
+// RegisterDeviceRequest represents the request payload for registering a
+// device for push notifications
+// @swagger:model
+type RegisterDeviceRequest struct {
+	// One of ios, android or web
+	// required: true
+	// example: ios
+	Platform models.DevicePlatform `json:"platform"`
+
+	// The APNs device token on iOS, or the Firebase Cloud Messaging
+	// registration token on Android and the web
+	// required: true
+	// example: 9f3c5e1a0b7d4c2e8f6a1b3d5c7e9f0a2b4c6d8e0f1a3b5c7d9e1f3a5b7c9d1e
+	Token string `json:"token"`
+
+	// example: 1.4.2
+	AppVersion string `json:"app_version"`
+}
+
+// DeviceResponse represents a device registered for push notifications
+// @swagger:model
+type DeviceResponse struct {
+	// example: 1
+	ID uint `json:"id"`
+
+	// example: ios
+	Platform models.DevicePlatform `json:"platform"`
+
+	// example: 1.4.2
+	AppVersion string `json:"app_version,omitempty"`
+
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func newDeviceResponse(device *models.Device) DeviceResponse {
+	return DeviceResponse{
+		ID:         device.ID,
+		Platform:   device.Platform,
+		AppVersion: device.AppVersion,
+		LastSeenAt: device.LastSeenAt,
+		CreatedAt:  device.CreatedAt,
+	}
+}
+
+// validate normalizes the request and returns a message describing the
+// first invalid field
+func (req *RegisterDeviceRequest) validate() string {
+	req.Token = strings.TrimSpace(req.Token)
+	req.AppVersion = strings.TrimSpace(req.AppVersion)
+	switch req.Platform {
+	case models.DeviceIOS:
+		req.Token = strings.ToLower(req.Token)
+		if !apnsTokenPattern.MatchString(req.Token) {
+			return "Invalid APNs device token"
+		}
+	case models.DeviceAndroid, models.DeviceWeb:
+		if !fcmTokenPattern.MatchString(req.Token) {
+			return "Invalid FCM registration token"
+		}
+	default:
+		return "Platform must be \"ios\", \"android\" or \"web\""
+	}
+	if len(req.AppVersion) > maxAppVersionLength {
+		return "App version must be at most 50 characters"
+	}
+	return ""
+}
+
+// registerDeviceHandler godoc
+// @Summary Register a device for push notifications
+// @Description Register the push token of the app on one of the authenticated user's devices. The app should call this on every launch and whenever its token changes; registering a known token moves it to the caller and refreshes it.
+// @Tags devices
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param device body RegisterDeviceRequest true "Device"
+// @Success 200 {object} DeviceResponse "The token was already registered"
+// @Success 201 {object} DeviceResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /devices [post]
+func registerDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RegisterDeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	device := &models.Device{
+		UserID:     currentUserID(r),
+		Platform:   req.Platform,
+		Token:      req.Token,
+		AppVersion: req.AppVersion,
+		LastSeenAt: time.Now(),
+	}
+	created, err := deviceRepo.Register(device)
+	if err != nil {
+		http.Error(w, "Failed to register device", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(newDeviceResponse(device))
+}
+
+// deleteDeviceHandler godoc
+// @Summary Unregister a device
+// @Description Stop sending push notifications to one of the authenticated user's devices, for example when they sign out of the app on it
+// @Tags devices
+// @Security ApiKeyAuth
+// @Param id path int true "Device ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /devices/{id} [delete]
+func deleteDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	deviceID, ok := pathID(w, r, "id", "device")
+	if !ok {
+		return
+	}
+
+	if err := deviceRepo.Delete(currentUserID(r), deviceID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Device not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to unregister device", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
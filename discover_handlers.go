@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/connectplus/repositories"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultDiscoverLimit = 20
+	maxDiscoverLimit     = 50
+)
+
+// discoverHandler godoc
+// @Summary Get discovery feed
+// @Description Get candidate profiles for the authenticated user, filtered by their age preferences. Once the caller has set a location, results are also limited to their match distance and ordered nearest first. Users already swiped on or matched are excluded. Distance is left out for users who have chosen to hide it.
+// @Tags discovery
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param limit query int false "Maximum number of profiles to return (default 20, max 50)"
+// @Param offset query int false "Number of profiles to skip"
+// @Success 200 {array} ProfileResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /discover [get]
+func discoverHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, offset, ok := parseLimitOffset(w, r, defaultDiscoverLimit, maxDiscoverLimit)
+	if !ok {
+		return
+	}
+
+	userID := currentUserID(r)
+	preference, err := findPreferenceOrDefault(userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	filter := repositories.DiscoveryFilter{
+		UserID:        userID,
+		MinAge:        preference.MinAge,
+		MaxAge:        preference.MaxAge,
+		MaxDistanceKm: preference.MatchDistance,
+		Limit:         limit,
+		Offset:        offset,
+		// A pair who unmatched stay hidden from each other for a while
+		RematchCooldown: cfg.RematchCooldown,
+	}
+
+	// Distance is only enforced once the caller has shared a position
+	viewer, err := profileRepo.FindByUserID(userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err == nil && viewer.HasCoordinates() {
+		filter.Latitude = viewer.Latitude
+		filter.Longitude = viewer.Longitude
+	}
+
+	profiles, err := profileRepo.Discover(filter)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := newViewedProfileResponses(userID, profiles)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// parseLimitOffset reads the limit and offset query parameters, writing a
+// 400 response and returning ok=false if either is malformed
+func parseLimitOffset(w http.ResponseWriter, r *http.Request, defaultLimit, maxLimit int) (limit, offset int, ok bool) {
+	limit = defaultLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return 0, 0, false
+		}
+		limit = n
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return 0, 0, false
+		}
+		offset = n
+	}
+
+	return limit, offset, true
+}
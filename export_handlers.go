@@ -0,0 +1,465 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/connectplus/models"
+	"github.com/connectplus/repositories"
+	"github.com/connectplus/storage"
+	"gorm.io/gorm"
+)
+
+// dataExportTimeout bounds how long building one archive may take
+const dataExportTimeout = 10 * time.Minute
+
+// dataExportSlots limits how many archives are built at once, since each
+// one copies all of the user's photos
+var dataExportSlots = make(chan struct{}, 2)
+
+// DataExportResponse describes a data export request
+// @swagger:model
+type DataExportResponse struct {
+	// example: 12
+	ID uint `json:"id"`
+
+	// One of pending, ready, failed or expired
+	// example: ready
+	Status models.DataExportStatus `json:"status"`
+
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+
+	// Archive size in bytes, once ready
+	SizeBytes int64 `json:"size_bytes,omitempty"`
+
+	// Link to download the archive without further authentication. Only set
+	// while the export is ready; it stops working at expires_at.
+	DownloadURL string     `json:"download_url,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+func newDataExportResponse(export *models.DataExport) DataExportResponse {
+	resp := DataExportResponse{
+		ID:          export.ID,
+		Status:      export.Status,
+		CreatedAt:   export.CreatedAt,
+		CompletedAt: export.CompletedAt,
+	}
+	if export.IsDownloadable(time.Now()) {
+		resp.SizeBytes = export.SizeBytes
+		resp.DownloadURL = dataExportDownloadURL(export)
+		resp.ExpiresAt = export.ExpiresAt
+	}
+	return resp
+}
+
+// requestDataExportHandler godoc
+// @Summary Request a data export
+// @Description Start building an archive of everything stored about the caller: their account, profile, preferences, matches, swipes, blocks, reports they filed, all messages sent and received, notifications, registered devices, and their photos. Poll the status endpoint for the download link.
+// @Tags users
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 202 {object} DataExportResponse
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /user/export [post]
+func requestDataExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	export := &models.DataExport{UserID: currentUserID(r)}
+	if err := dataExportRepo.Create(export); err != nil {
+		if errors.Is(err, repositories.ErrExportPending) {
+			http.Error(w, "A data export is already being prepared", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to request data export", http.StatusInternalServerError)
+		return
+	}
+	go buildDataExport(export.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(newDataExportResponse(export))
+}
+
+// dataExportStatusHandler godoc
+// @Summary Get data export status
+// @Description Get the status of one of the caller's data exports, with a download link once it is ready.
+// @Tags users
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Export ID"
+// @Success 200 {object} DataExportResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /user/export/{id} [get]
+func dataExportStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	exportID, ok := pathID(w, r, "id", "export")
+	if !ok {
+		return
+	}
+	export, err := dataExportRepo.FindByID(exportID)
+	if err != nil || export.UserID != currentUserID(r) {
+		if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Export not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newDataExportResponse(export))
+}
+
+// downloadDataExportHandler godoc
+// @Summary Download a data export
+// @Description Download a ready data export as a zip archive. The link from the status endpoint is signed, so no token is needed; it stops working when the export expires.
+// @Tags users
+// @Produce  application/zip
+// @Param id path int true "Export ID"
+// @Param expires query int true "Link expiry as a Unix timestamp"
+// @Param signature query string true "Link signature"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 410 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /user/export/{id}/download [get]
+func downloadDataExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	exportID, ok := pathID(w, r, "id", "export")
+	if !ok {
+		return
+	}
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil || !hmac.Equal([]byte(r.URL.Query().Get("signature")), []byte(signDataExport(exportID, expires))) {
+		http.Error(w, "Invalid download link", http.StatusNotFound)
+		return
+	}
+	if time.Now().Unix() >= expires {
+		http.Error(w, "Download link has expired", http.StatusGone)
+		return
+	}
+
+	export, err := dataExportRepo.FindByID(exportID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Export not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !export.IsDownloadable(time.Now()) {
+		http.Error(w, "Download link has expired", http.StatusGone)
+		return
+	}
+
+	archive, err := blobs.Get(r.Context(), export.BlobKey)
+	if err != nil {
+		log.Printf("Failed to open data export %d: %v", export.ID, err)
+		http.Error(w, "Failed to read export", http.StatusInternalServerError)
+		return
+	}
+	defer archive.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="connectplus-export-%d.zip"`, export.ID))
+	w.Header().Set("Content-Length", strconv.FormatInt(export.SizeBytes, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	io.Copy(w, archive)
+}
+
+// dataExportDownloadURL returns a link to the export's archive that is valid
+// until the export expires
+func dataExportDownloadURL(export *models.DataExport) string {
+	expires := export.ExpiresAt.Unix()
+	return fmt.Sprintf("%s/user/export/%d/download?expires=%d&signature=%s",
+		cfg.BaseURL, export.ID, expires, signDataExport(export.ID, expires))
+}
+
+// signDataExport returns the hex HMAC-SHA256 authorising a download of the
+// export until the given Unix time
+func signDataExport(exportID uint, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
+	fmt.Fprintf(mac, "data-export:%d:%d", exportID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// exportedUser is the user's account row as exported: everything except the
+// password hash
+type exportedUser struct {
+	ID                  uint
+	Email               string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	LastLoginAt         time.Time
+	LastActiveAt        *time.Time
+	IsActive            bool
+	IsVerified          bool
+	Role                models.Role
+	DeactivatedAt       *time.Time
+	DeletionScheduledAt *time.Time
+}
+
+// exportedData is the data.json file of an export archive. Rows are exported
+// as stored, so columns added to the models later are included too.
+type exportedData struct {
+	ExportedAt    time.Time             `json:"exported_at"`
+	User          exportedUser          `json:"user"`
+	Profile       *models.Profile       `json:"profile"`
+	Preference    *models.Preference    `json:"preference"`
+	Matches       []models.Match        `json:"matches"`
+	Swipes        []models.Swipe        `json:"swipes"`
+	Messages      []models.Message      `json:"messages"`
+	Blocks        []models.Block        `json:"blocks"`
+	Reports       []models.Report       `json:"reports"`
+	Notifications []models.Notification `json:"notifications"`
+	Devices       []models.Device       `json:"devices"`
+}
+
+// buildDataExport builds and stores the archive for a pending export,
+// marking it failed if that isn't possible
+func buildDataExport(exportID uint) {
+	dataExportSlots <- struct{}{}
+	defer func() { <-dataExportSlots }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), dataExportTimeout)
+	defer cancel()
+
+	if err := writeDataExport(ctx, exportID); err != nil {
+		log.Printf("Data export %d failed: %v", exportID, err)
+		if err := dataExportRepo.MarkFailed(exportID); err != nil {
+			log.Printf("Failed to mark data export %d failed: %v", exportID, err)
+		}
+	}
+}
+
+func writeDataExport(ctx context.Context, exportID uint) error {
+	export, err := dataExportRepo.FindByID(exportID)
+	if err != nil {
+		return err
+	}
+
+	// Archives hold every photo, so build them on disk rather than in memory
+	tmp, err := os.CreateTemp("", "data-export-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := writeDataArchive(ctx, tmp, export.UserID); err != nil {
+		return err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	key, err := dataExportKey(export.UserID)
+	if err != nil {
+		return err
+	}
+	if err := blobs.Put(ctx, key, tmp, "application/zip"); err != nil {
+		return err
+	}
+
+	if err := dataExportRepo.MarkReady(export.ID, key, size, time.Now().Add(cfg.DataExportTTL)); err != nil {
+		blobs.Delete(ctx, key)
+		return err
+	}
+	return nil
+}
+
+// writeDataArchive writes a zip of the user's data.json and photos to w
+func writeDataArchive(ctx context.Context, w io.Writer, userID uint) error {
+	user, err := userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	data := exportedData{
+		ExportedAt: time.Now().UTC(),
+		User: exportedUser{
+			ID:                  user.ID,
+			Email:               user.Email,
+			CreatedAt:           user.CreatedAt,
+			UpdatedAt:           user.UpdatedAt,
+			LastLoginAt:         user.LastLoginAt,
+			LastActiveAt:        user.LastActiveAt,
+			IsActive:            user.IsActive,
+			IsVerified:          user.IsVerified,
+			Role:                user.Role,
+			DeactivatedAt:       user.DeactivatedAt,
+			DeletionScheduledAt: user.DeletionScheduledAt,
+		},
+	}
+
+	profile, err := profileRepo.FindByUserID(userID)
+	switch {
+	case err == nil:
+		data.Profile = profile
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+	preference, err := preferenceRepo.FindByUserID(userID)
+	switch {
+	case err == nil:
+		data.Preference = preference
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+	if data.Matches, err = matchRepo.FindByUserID(userID); err != nil {
+		return err
+	}
+	if data.Swipes, err = swipeRepo.FindBySwiperID(userID); err != nil {
+		return err
+	}
+	if data.Messages, err = messageRepo.FindAllByUserID(userID); err != nil {
+		return err
+	}
+	if data.Blocks, err = blockRepo.FindByBlockerID(userID); err != nil {
+		return err
+	}
+	if data.Reports, err = reportRepo.FindByReporterID(userID); err != nil {
+		return err
+	}
+	if data.Notifications, err = notificationRepo.FindAllByUserID(userID); err != nil {
+		return err
+	}
+	if data.Devices, err = deviceRepo.FindByUserID(userID); err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	f, err := zw.Create("data.json")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(data); err != nil {
+		return err
+	}
+
+	if data.Profile != nil {
+		for _, url := range data.Profile.Photos {
+			if err := addPhotoToArchive(ctx, zw, userID, photoIDFromURL(url)); err != nil {
+				return err
+			}
+		}
+	}
+	return zw.Close()
+}
+
+// addPhotoToArchive copies a full-size photo into the archive. Photos whose
+// blob is missing are skipped rather than failing the export.
+func addPhotoToArchive(ctx context.Context, zw *zip.Writer, userID uint, photoID string) error {
+	blob, err := blobs.Get(ctx, photoKey(userID, photoID))
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Printf("Photo %s of user %d is missing, leaving it out of their export", photoID, userID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+
+	f, err := zw.Create("photos/" + photoID + ".jpg")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, blob)
+	return err
+}
+
+// resumeDataExports restarts exports left pending by a previous run of the
+// server
+func resumeDataExports() {
+	exports, err := dataExportRepo.FindByStatus(models.DataExportPending)
+	if err != nil {
+		log.Printf("Failed to load pending data exports: %v", err)
+		return
+	}
+	for _, export := range exports {
+		go buildDataExport(export.ID)
+	}
+}
+
+// purgeExpiredDataExports deletes the archives of exports that have expired
+func purgeExpiredDataExports(ctx context.Context) {
+	exports, err := dataExportRepo.FindExpired(time.Now())
+	if err != nil {
+		log.Printf("Data export purge failed: %v", err)
+		return
+	}
+	for _, export := range exports {
+		if err := blobs.Delete(ctx, export.BlobKey); err != nil {
+			log.Printf("Failed to delete data export %d: %v", export.ID, err)
+			continue
+		}
+		if err := dataExportRepo.MarkExpired(export.ID); err != nil {
+			log.Printf("Failed to mark data export %d expired: %v", export.ID, err)
+		}
+	}
+}
+
+// deleteDataExportBlobs deletes every stored export archive of the user
+func deleteDataExportBlobs(ctx context.Context, userID uint) error {
+	exports, err := dataExportRepo.FindByUserID(userID)
+	if err != nil {
+		return err
+	}
+	for _, export := range exports {
+		if export.BlobKey == "" {
+			continue
+		}
+		if err := blobs.Delete(ctx, export.BlobKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dataExportKey returns a new random blob key for one of the user's export
+// archives, so keys can't be guessed
+func dataExportKey(userID uint) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("exports/%d/%s.zip", userID, hex.EncodeToString(b)), nil
+}
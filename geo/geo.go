@@ -0,0 +1,57 @@
+// Package geo provides the distance math used to match users by location.
+package geo
+
+import "math"
+
+// EarthRadiusKm is the mean radius of the Earth in kilometers.
+const EarthRadiusKm = 6371.0
+
+// ValidCoordinates reports whether lat and lon are a valid WGS84 position.
+func ValidCoordinates(lat, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
+
+// Distance returns the great-circle distance in kilometers between two
+// points using the haversine formula.
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// BoundingBox is a latitude/longitude rectangle.
+type BoundingBox struct {
+	MinLat, MaxLat float64
+	MinLon, MaxLon float64
+}
+
+// NewBoundingBox returns the smallest rectangle containing every point
+// within radiusKm of (lat, lon). It is meant as a cheap, indexable
+// prefilter; callers must still check Distance for exact results. Near the
+// poles or across the antimeridian the box widens to every longitude.
+func NewBoundingBox(lat, lon, radiusKm float64) BoundingBox {
+	dLat := radiusKm / EarthRadiusKm * 180 / math.Pi
+	box := BoundingBox{
+		MinLat: math.Max(lat-dLat, -90),
+		MaxLat: math.Min(lat+dLat, 90),
+		MinLon: -180,
+		MaxLon: 180,
+	}
+	if box.MinLat == -90 || box.MaxLat == 90 {
+		return box
+	}
+
+	dLon := math.Asin(math.Min(1, math.Sin(radiusKm/EarthRadiusKm)/math.Cos(toRadians(lat)))) * 180 / math.Pi
+	if lon-dLon < -180 || lon+dLon > 180 {
+		return box
+	}
+	box.MinLon = lon - dLon
+	box.MaxLon = lon + dLon
+	return box
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -0,0 +1,43 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistance(t *testing.T) {
+	// San Francisco to Los Angeles is roughly 559 km
+	d := Distance(37.7749, -122.4194, 34.0522, -118.2437)
+	assert.InDelta(t, 559, d, 5)
+
+	assert.Zero(t, Distance(10, 20, 10, 20))
+}
+
+func TestBoundingBoxContainsRadius(t *testing.T) {
+	lat, lon, radius := 37.7749, -122.4194, 50.0
+	box := NewBoundingBox(lat, lon, radius)
+
+	// Points exactly radius km due north, south, east and west lie inside
+	north := lat + radius/EarthRadiusKm*180/3.141592653589793
+	assert.LessOrEqual(t, north, box.MaxLat+1e-9)
+	assert.InDelta(t, radius, Distance(lat, lon, lat, box.MaxLon), 1)
+	assert.InDelta(t, radius, Distance(lat, lon, lat, box.MinLon), 1)
+}
+
+func TestBoundingBoxWrapsAtEdges(t *testing.T) {
+	box := NewBoundingBox(89.9, 0, 50)
+	assert.Equal(t, -180.0, box.MinLon)
+	assert.Equal(t, 180.0, box.MaxLon)
+
+	box = NewBoundingBox(0, 179.9, 50)
+	assert.Equal(t, -180.0, box.MinLon)
+	assert.Equal(t, 180.0, box.MaxLon)
+}
+
+func TestValidCoordinates(t *testing.T) {
+	assert.True(t, ValidCoordinates(0, 0))
+	assert.True(t, ValidCoordinates(-90, 180))
+	assert.False(t, ValidCoordinates(91, 0))
+	assert.False(t, ValidCoordinates(0, -181))
+}
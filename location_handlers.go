@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/connectplus/geo"
+	"gorm.io/gorm"
+)
+
+// UpdateLocationRequest represents the request payload for updating a user's position
+// @swagger:model
+type UpdateLocationRequest struct {
+	// Latitude in decimal degrees
+	// required: true
+	// example: 37.7749
+	Latitude *float64 `json:"latitude"`
+
+	// Longitude in decimal degrees
+	// required: true
+	// example: -122.4194
+	Longitude *float64 `json:"longitude"`
+}
+
+// updateLocationHandler godoc
+// @Summary Update profile location
+// @Description Set the authenticated user's current position, used to enforce match distance in discovery
+// @Tags profiles
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param location body UpdateLocationRequest true "Current position"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /profile/location [put]
+func updateLocationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req UpdateLocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.Latitude == nil || req.Longitude == nil {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+	if !geo.ValidCoordinates(*req.Latitude, *req.Longitude) {
+		http.Error(w, "Invalid coordinates", http.StatusBadRequest)
+		return
+	}
+
+	err := profileRepo.UpdateLocation(currentUserID(r), *req.Latitude, *req.Longitude)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Profile not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to update location", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Location updated successfully",
+	})
+}
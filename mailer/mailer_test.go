@@ -0,0 +1,111 @@
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	m := &LogMailer{Logger: log.New(&buf, "", 0)}
+
+	err := m.Send(context.Background(), Message{To: "a@example.com", Subject: "Hi", Body: "Hello"})
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "a@example.com")
+	assert.Contains(t, buf.String(), "Hello")
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m := &FileMailer{Dir: filepath.Join(dir, "mail")}
+
+	assert.NoError(t, m.Send(context.Background(), Message{To: "a@example.com", Subject: "First", Body: "one"}))
+	assert.NoError(t, m.Send(context.Background(), Message{To: "b@example.com", Subject: "Second", Body: "two"}))
+
+	entries, err := os.ReadDir(m.Dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	content, err := os.ReadFile(filepath.Join(m.Dir, entries[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "Subject: First")
+}
+
+// fakeSMTPServer accepts a single SMTP session and returns the DATA payload.
+func fakeSMTPServer(t *testing.T) (addr string, data <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	out := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "DATA"):
+				reply("354 go ahead")
+				var body strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					body.WriteString(l)
+				}
+				out <- body.String()
+				reply("250 queued")
+			case strings.HasPrefix(cmd, "QUIT"):
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return ln.Addr().String(), out
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, data := fakeSMTPServer(t)
+	host, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+
+	portNum, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	m := &SMTPMailer{Host: host, Port: portNum, From: "noreply@connectplus.com"}
+
+	err = m.Send(context.Background(), Message{To: "a@example.com", Subject: "Verify", Body: "line one\nline two"})
+	require.NoError(t, err)
+
+	payload := <-data
+	assert.Contains(t, payload, "To: a@example.com\r\n")
+	assert.Contains(t, payload, "Subject: Verify\r\n")
+	assert.Contains(t, payload, "line one\r\nline two")
+}
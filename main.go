@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,8 +11,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/connectplus/cache"
+	"github.com/connectplus/mailer"
 	"github.com/connectplus/models"
+	"github.com/connectplus/presence"
 	"github.com/connectplus/repositories"
+	"github.com/connectplus/storage"
 	"golang.org/x/crypto/bcrypt"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/swaggo/http-swagger"
@@ -26,6 +31,28 @@ var (
 	matchRepo repositories.MatchRepository
 	messageRepo repositories.MessageRepository
 	preferenceRepo repositories.PreferenceRepository
+	swipeRepo repositories.SwipeRepository
+	sessionRepo repositories.SessionRepository
+	userTokenRepo repositories.UserTokenRepository
+	blockRepo repositories.BlockRepository
+	reportRepo repositories.ReportRepository
+	auditLogRepo repositories.AuditLogRepository
+	dataExportRepo repositories.DataExportRepository
+	notificationRepo repositories.NotificationRepository
+	deviceRepo repositories.DeviceRepository
+	mail mailer.Mailer
+	blobs storage.BlobStore
+)
+
+type contextKey string
+
+const (
+	// userIDKey holds the authenticated user's ID in the request context
+	userIDKey contextKey = "user_id"
+	// sessionIDKey holds the ID of the session the access token belongs to
+	sessionIDKey contextKey = "session_id"
+	// roleKey holds the authenticated user's role
+	roleKey contextKey = "role"
 )
 
 // User represents a Connect+ user profile
@@ -74,6 +101,10 @@ type CreateUserResponse struct {
 	// JWT token for authentication
 	// example: eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9...
 	Token    string `json:"token"`
+
+	// Refresh token used to obtain new access tokens
+	// example: 3q2-7wEAAAB0aGlzIGlzIG5vdCBhIHJlYWwgdG9rZW4...
+	RefreshToken string `json:"refresh_token"`
 }
 
 func initDB() error {
@@ -90,6 +121,15 @@ func initDB() error {
 	matchRepo = repositories.NewMatchRepository(db)
 	messageRepo = repositories.NewMessageRepository(db)
 	preferenceRepo = repositories.NewPreferenceRepository(db)
+	swipeRepo = repositories.NewSwipeRepository(db)
+	sessionRepo = repositories.NewSessionRepository(db)
+	userTokenRepo = repositories.NewUserTokenRepository(db)
+	blockRepo = repositories.NewBlockRepository(db)
+	reportRepo = repositories.NewReportRepository(db)
+	auditLogRepo = repositories.NewAuditLogRepository(db)
+	dataExportRepo = repositories.NewDataExportRepository(db)
+	notificationRepo = repositories.NewNotificationRepository(db)
+	deviceRepo = repositories.NewDeviceRepository(db)
 
 	// Auto migrate models
 	err = db.AutoMigrate(
@@ -98,6 +138,16 @@ func initDB() error {
 		&models.Match{},
 		&models.Message{},
 		&models.Preference{},
+		&models.Swipe{},
+		&models.Session{},
+		&models.UserToken{},
+		&models.MatchEvent{},
+		&models.Block{},
+		&models.Report{},
+		&models.AuditLog{},
+		&models.DataExport{},
+		&models.Notification{},
+		&models.Device{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to auto migrate models: %w", err)
@@ -148,6 +198,11 @@ func rootHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 500 {object} map[string]string
 // @Router /user [get]
 func userHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodDelete {
+		deleteAccountHandler(w, r)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 
 	// Get user ID from JWT token
@@ -216,7 +271,7 @@ func createUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate password strength
-	if len(req.Password) < 8 {
+	if len(req.Password) < minPasswordLength {
 		http.Error(w, "Password must be at least 8 characters", http.StatusBadRequest)
 		return
 	}
@@ -236,15 +291,16 @@ func createUserHandler(w http.ResponseWriter, r *http.Request) {
 	// Create user in database
 	user := &models.User{
 		Email:    req.Email,
+		Role:     models.RoleUser,
 	}
 	
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := hashPassword(req.Password)
 	if err != nil {
 		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
 		return
 	}
-	user.PasswordHash = string(hashedPassword)
+	user.PasswordHash = hashedPassword
 
 	// Create user
 	result = db.Create(user)
@@ -253,8 +309,15 @@ func createUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate token
-	token, err := generateToken(int(user.ID))
+	// Send verification email without holding up signup
+	go func() {
+		if err := sendVerificationEmail(context.Background(), user); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		}
+	}()
+
+	// Generate tokens
+	token, refreshToken, err := issueSession(user, r)
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
@@ -263,22 +326,42 @@ func createUserHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(CreateUserResponse{
-		ID:       int(user.ID),
-		Username: req.Username,
-		Email:    req.Email,
-		Token:    token,
+		ID:           int(user.ID),
+		Username:     req.Username,
+		Email:        req.Email,
+		Token:        token,
+		RefreshToken: refreshToken,
 	})
 }
 
-func generateToken(userID int) (string, error) {
+func generateToken(userID int, role models.Role, sessionID uint) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
+		"role":    role,
+		"sid":     sessionID,
+		"exp":     time.Now().Add(accessTokenTTL).Unix(),
 	})
 
 	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
 }
 
+// minPasswordLength is the shortest password accepted at signup, reset or change
+const minPasswordLength = 8
+
+// hashPassword returns the bcrypt hash stored for a password
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// checkPassword reports whether password matches a stored bcrypt hash
+func checkPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
 // authMiddleware verifies JWT tokens for protected routes
 func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -300,15 +383,103 @@ func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			return
+		}
+		userID, ok := claims["user_id"].(float64)
+		if !ok {
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			return
+		}
+		sessionID, ok := claims["sid"].(float64)
+		if !ok {
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		// Reject tokens whose session was revoked by logout or password reset
+		session, err := sessionRepo.FindByID(uint(sessionID))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				return
+			}
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if session.UserID != uint(userID) || !session.IsActive(time.Now()) {
+			http.Error(w, "Session has been revoked", http.StatusUnauthorized)
+			return
+		}
+
+		// Suspended and deactivated users lose access straight away rather
+		// than when their access token expires
+		active, err := isUserActive(uint(userID))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				return
+			}
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if !active {
+			http.Error(w, "Account is inactive", http.StatusForbidden)
+			return
+		}
+		presenceStore.Touch(uint(userID))
+
+		// Tokens issued before roles existed carry none
+		role := models.RoleUser
+		if claim, ok := claims["role"].(string); ok {
+			role = models.Role(claim)
+		}
+
+		ctx := context.WithValue(r.Context(), userIDKey, uint(userID))
+		ctx = context.WithValue(ctx, sessionIDKey, session.ID)
+		ctx = context.WithValue(ctx, roleKey, role)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
 
+// roleAuthMiddleware is authMiddleware for routes that need at least the
+// given role. Roles are read from the access token, so a role change takes
+// effect when the user's token is next refreshed.
+func roleAuthMiddleware(role models.Role, next http.HandlerFunc) http.HandlerFunc {
+	return authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if !currentRole(r).AtLeast(role) {
+			http.Error(w, "Insufficient permissions", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// currentUserID returns the ID of the user authenticated by authMiddleware
+func currentUserID(r *http.Request) uint {
+	userID, _ := r.Context().Value(userIDKey).(uint)
+	return userID
+}
+
+// currentRole returns the role of the user authenticated by authMiddleware
+func currentRole(r *http.Request) models.Role {
+	role, _ := r.Context().Value(roleKey).(models.Role)
+	return role
+}
+
+// currentSessionID returns the ID of the session authenticated by authMiddleware
+func currentSessionID(r *http.Request) uint {
+	sessionID, _ := r.Context().Value(sessionIDKey).(uint)
+	return sessionID
+}
+
 // corsMiddleware handles CORS requests
 func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, PATCH, DELETE")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 		
 		// Handle preflight requests
@@ -330,108 +501,6 @@ func loggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-// UpdateProfileRequest represents the request payload for updating a user profile
-// @swagger:model
-type UpdateProfileRequest struct {
-	// First name
-	// example: John
-	FirstName        string `json:"first_name"`
-	
-	// Last name
-	// example: Doe
-	LastName         string `json:"last_name"`
-	
-	// User bio
-	// example: Love hiking and photography
-	Bio              string `json:"bio"`
-	
-	// Gender identity
-	// example: Male
-	GenderIdentity   string `json:"gender_identity"`
-	
-	// Sexual orientation
-	// example: Heterosexual
-	SexualOrientation string `json:"sexual_orientation"`
-	
-	// Profile picture URL
-	// example: https://example.com/profile.jpg
-	ProfilePictureURL string `json:"profile_picture_url"`
-	
-	// Date of birth (YYYY-MM-DD)
-	// example: 1990-01-01
-	DateOfBirth      string `json:"date_of_birth"`
-	
-	// Location
-	// example: San Francisco, CA
-	Location         string `json:"location"`
-}
-
-// updateProfileHandler godoc
-// @Summary Update user profile
-// @Description Update the authenticated user's profile information
-// @Tags users
-// @Accept  json
-// @Produce  json
-// @Security ApiKeyAuth
-// @Param profile body UpdateProfileRequest true "Profile update details"
-// @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 500 {object} map[string]string
-// @Router /user/profile [put]
-func updateProfileHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPut {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Get user ID from JWT token
-	tokenString := r.Header.Get("Authorization")
-	token, _ := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
-	claims := token.Claims.(jwt.MapClaims)
-	userID := int(claims["user_id"].(float64))
-
-	var req UpdateProfileRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
-		return
-	}
-
-	// Validate date of birth format if provided
-	if req.DateOfBirth != "" {
-		_, err := time.Parse("2006-01-02", req.DateOfBirth)
-		if err != nil {
-			http.Error(w, "Invalid date format. Use YYYY-MM-DD", http.StatusBadRequest)
-			return
-		}
-	}
-
-	// Update profile in database
-	result := db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
-		"first_name":         req.FirstName,
-		"last_name":          req.LastName,
-		"bio":                req.Bio,
-		"gender_identity":    req.GenderIdentity,
-		"sexual_orientation": req.SexualOrientation,
-		"profile_picture_url": req.ProfilePictureURL,
-		"date_of_birth":      req.DateOfBirth,
-		"location":           req.Location,
-	})
-
-	if result.Error != nil {
-		http.Error(w, "Failed to update profile", http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Profile updated successfully",
-	})
-}
-
 // LoginRequest represents the login credentials
 type LoginRequest struct {
 	Email    string `json:"email"`
@@ -440,8 +509,9 @@ type LoginRequest struct {
 
 // LoginResponse represents the login response
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	User         User   `json:"user"`
 }
 
 func loginHandler(w http.ResponseWriter, r *http.Request) {
@@ -469,14 +539,38 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify password
-	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
-	if err != nil {
+	if !checkPassword(user.PasswordHash, req.Password) {
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	// Generate token
-	token, err := generateToken(int(user.ID))
+	// Checked after the password so the account's status isn't revealed to
+	// anyone who knows the email address. Users who deactivated their own
+	// account get it back, cancelling any scheduled deletion; suspended
+	// users stay locked out.
+	if !user.IsActive {
+		if user.DeactivatedAt == nil {
+			http.Error(w, "Account is inactive", http.StatusForbidden)
+			return
+		}
+		if err := userRepo.Reactivate(user.ID); err != nil {
+			http.Error(w, "Failed to reactivate account", http.StatusInternalServerError)
+			return
+		}
+		activeUsers.Delete(user.ID)
+		user.IsActive = true
+		user.DeactivatedAt = nil
+		user.DeletionScheduledAt = nil
+	}
+
+	user.LastLoginAt = time.Now()
+	if err := userRepo.SetLastLogin(user.ID, user.LastLoginAt); err != nil {
+		log.Printf("Failed to record login for user %d: %v", user.ID, err)
+	}
+	presenceStore.Touch(user.ID)
+
+	// Generate tokens
+	token, refreshToken, err := issueSession(&user, r)
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
@@ -484,20 +578,36 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(LoginResponse{
-		Token: token,
-		User: User{User: user},
+		Token:        token,
+		RefreshToken: refreshToken,
+		User:         User{User: user},
 	})
 }
 
 func main() {
 	// Initialize logging
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
+
+	cfg = loadConfig()
+	mail = newMailer(cfg)
+	blobs = newBlobStore(cfg)
+	activeUsers = cache.NewTTL[uint, bool](cfg.ActiveUserCacheTTL)
+	presenceStore = presence.NewMemory(cfg.PresenceOnlineWindow)
+	notifier = newNotifier(cfg)
 	
 	// Initialize database connection
 	if err := initDB(); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 		return
 	}
+	promoteAdmins(cfg.AdminUserIDs)
+
+	if cfg.MatchExpiry > 0 {
+		go runMatchExpirySweep(cfg.MatchExpiry, cfg.MatchExpirySweepInterval)
+	}
+	go runAccountPurge(cfg.AccountPurgeInterval)
+	go runPresenceFlush(cfg.PresenceFlushInterval)
+	resumeDataExports()
 
 	// Create a new ServeMux to handle routes
 	mux := http.NewServeMux()
@@ -506,11 +616,59 @@ func main() {
 	mux.HandleFunc("/", corsMiddleware(loggingMiddleware(rootHandler)))
 	mux.HandleFunc("/user/create", corsMiddleware(loggingMiddleware(createUserHandler)))
 	mux.HandleFunc("/user/login", corsMiddleware(loggingMiddleware(loginHandler)))
+	mux.HandleFunc("/auth/refresh", corsMiddleware(loggingMiddleware(refreshTokenHandler)))
+	mux.HandleFunc("/auth/verify", corsMiddleware(loggingMiddleware(verifyEmailHandler)))
+	mux.HandleFunc("/auth/password/forgot", corsMiddleware(loggingMiddleware(forgotPasswordHandler)))
+	mux.HandleFunc("/auth/password/reset", corsMiddleware(loggingMiddleware(resetPasswordHandler)))
+	mux.HandleFunc("/user/export/{id}/download", corsMiddleware(loggingMiddleware(downloadDataExportHandler)))
 	mux.HandleFunc("/swagger/", httpSwagger.WrapHandler)
+	if local, ok := blobs.(*storage.LocalStore); ok {
+		mux.Handle("/uploads/", uploadsHandler(local))
+	}
 
 	// Protected routes with logging and CORS
 	mux.HandleFunc("/user", corsMiddleware(loggingMiddleware(authMiddleware(userHandler))))
-	mux.HandleFunc("/user/profile", corsMiddleware(loggingMiddleware(authMiddleware(updateProfileHandler))))
+	mux.HandleFunc("/user/deactivate", corsMiddleware(loggingMiddleware(authMiddleware(deactivateAccountHandler))))
+	mux.HandleFunc("/user/export", corsMiddleware(loggingMiddleware(authMiddleware(requestDataExportHandler))))
+	mux.HandleFunc("/user/export/{id}", corsMiddleware(loggingMiddleware(authMiddleware(dataExportStatusHandler))))
+	mux.HandleFunc("/user/password", corsMiddleware(loggingMiddleware(authMiddleware(changePasswordHandler))))
+	mux.HandleFunc("/profile", corsMiddleware(loggingMiddleware(authMiddleware(profileHandler))))
+	mux.HandleFunc("/profiles/{userID}", corsMiddleware(loggingMiddleware(authMiddleware(getUserProfileHandler))))
+	mux.HandleFunc("/auth/logout", corsMiddleware(loggingMiddleware(authMiddleware(logoutHandler))))
+	mux.HandleFunc("/auth/logout-all", corsMiddleware(loggingMiddleware(authMiddleware(logoutAllHandler))))
+	mux.HandleFunc("/auth/verify/resend", corsMiddleware(loggingMiddleware(authMiddleware(resendVerificationHandler))))
+	mux.HandleFunc("/swipes", corsMiddleware(loggingMiddleware(authMiddleware(createSwipeHandler))))
+	mux.HandleFunc("/preferences", corsMiddleware(loggingMiddleware(authMiddleware(preferencesHandler))))
+	mux.HandleFunc("/notifications", corsMiddleware(loggingMiddleware(authMiddleware(listNotificationsHandler))))
+	mux.HandleFunc("/notifications/read", corsMiddleware(loggingMiddleware(authMiddleware(markNotificationsReadHandler))))
+	mux.HandleFunc("/devices", corsMiddleware(loggingMiddleware(authMiddleware(registerDeviceHandler))))
+	mux.HandleFunc("/devices/{id}", corsMiddleware(loggingMiddleware(authMiddleware(deleteDeviceHandler))))
+	mux.HandleFunc("/discover", corsMiddleware(loggingMiddleware(authMiddleware(requireVerified(discoverHandler)))))
+	mux.HandleFunc("/profile/location", corsMiddleware(loggingMiddleware(authMiddleware(updateLocationHandler))))
+	mux.HandleFunc("/profile/photos", corsMiddleware(loggingMiddleware(authMiddleware(photosHandler))))
+	mux.HandleFunc("/profile/photos/order", corsMiddleware(loggingMiddleware(authMiddleware(reorderPhotosHandler))))
+	mux.HandleFunc("/profile/photos/{photoID}", corsMiddleware(loggingMiddleware(authMiddleware(deletePhotoHandler))))
+	mux.HandleFunc("/ws/chat", corsMiddleware(loggingMiddleware(authMiddleware(requireVerified(chatHandler)))))
+	mux.HandleFunc("/conversations", corsMiddleware(loggingMiddleware(authMiddleware(requireVerified(listConversationsHandler)))))
+	mux.HandleFunc("/conversations/{userID}/messages", corsMiddleware(loggingMiddleware(authMiddleware(requireVerified(messagesHandler)))))
+	mux.HandleFunc("/matches", corsMiddleware(loggingMiddleware(authMiddleware(listMatchesHandler))))
+	mux.HandleFunc("/matches/{id}/accept", corsMiddleware(loggingMiddleware(authMiddleware(acceptMatchHandler))))
+	mux.HandleFunc("/matches/{id}/decline", corsMiddleware(loggingMiddleware(authMiddleware(declineMatchHandler))))
+	mux.HandleFunc("/matches/{id}/extend", corsMiddleware(loggingMiddleware(authMiddleware(extendMatchHandler))))
+	mux.HandleFunc("/matches/{id}", corsMiddleware(loggingMiddleware(authMiddleware(unmatchHandler))))
+	mux.HandleFunc("/users/{id}/block", corsMiddleware(loggingMiddleware(authMiddleware(blockUserHandler))))
+	mux.HandleFunc("/users/{id}/report", corsMiddleware(loggingMiddleware(authMiddleware(reportUserHandler))))
+
+	// Admin routes
+	mux.HandleFunc("/admin/reports", corsMiddleware(loggingMiddleware(roleAuthMiddleware(models.RoleModerator, adminListReportsHandler))))
+	mux.HandleFunc("/admin/reports/{id}/review", corsMiddleware(loggingMiddleware(roleAuthMiddleware(models.RoleModerator, adminReviewReportHandler))))
+	mux.HandleFunc("/admin/reports/{id}/messages", corsMiddleware(loggingMiddleware(roleAuthMiddleware(models.RoleModerator, adminReportMessagesHandler))))
+	mux.HandleFunc("/admin/matches/{id}/messages", corsMiddleware(loggingMiddleware(roleAuthMiddleware(models.RoleModerator, adminMatchMessagesHandler))))
+	mux.HandleFunc("/admin/users/{id}", corsMiddleware(loggingMiddleware(roleAuthMiddleware(models.RoleModerator, adminUserHandler))))
+	mux.HandleFunc("/admin/users/{id}/suspend", corsMiddleware(loggingMiddleware(roleAuthMiddleware(models.RoleModerator, adminSuspendUserHandler))))
+	mux.HandleFunc("/admin/users/{id}/reactivate", corsMiddleware(loggingMiddleware(roleAuthMiddleware(models.RoleModerator, adminReactivateUserHandler))))
+	mux.HandleFunc("/admin/users/{id}/role", corsMiddleware(loggingMiddleware(roleAuthMiddleware(models.RoleAdmin, adminSetRoleHandler))))
+	mux.HandleFunc("/admin/audit-log", corsMiddleware(loggingMiddleware(roleAuthMiddleware(models.RoleAdmin, adminAuditLogHandler))))
 	
 	fmt.Println("Server starting on port 8080")
 	err := http.ListenAndServe(":8080", mux)
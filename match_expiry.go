@@ -0,0 +1,23 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// runMatchExpirySweep expires stale matches every interval. It never
+// returns, so start it in its own goroutine.
+func runMatchExpirySweep(window, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := matchRepo.ExpireStale(window, time.Now())
+		if err != nil {
+			log.Printf("Match expiry sweep failed: %v", err)
+		} else if expired > 0 {
+			log.Printf("Expired %d matches with no messages", expired)
+		}
+		<-ticker.C
+	}
+}
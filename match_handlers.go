@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/connectplus/models"
+	"github.com/connectplus/privacy"
+	"github.com/connectplus/repositories"
+	"gorm.io/gorm"
+)
+
+// MatchResponse represents a match as seen by one of its participants
+// @swagger:model
+type MatchResponse struct {
+	// example: 12
+	ID uint `json:"id"`
+
+	// The other participant
+	// example: 2
+	UserID uint `json:"user_id"`
+
+	// example: Jane
+	DisplayName string `json:"display_name"`
+
+	// First profile photo of the other participant, if any
+	Photo string `json:"photo,omitempty"`
+
+	// One of "pending", "accepted", "declined", "unmatched", "expired" or
+	// "blocked"
+	// example: accepted
+	Status models.MatchStatus `json:"status"`
+
+	// Whether the match is pending and waiting for the caller to accept or
+	// decline it
+	CanRespond bool `json:"can_respond"`
+
+	// When the match expires unless someone sends a message. Only set for
+	// accepted matches without messages while match expiry is enabled.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Whether the match's one extension has been used
+	Extended bool `json:"extended"`
+
+	// Distance to the other participant and, once the match is accepted,
+	// whether they are online and when they were last active, unless they
+	// have chosen to hide them
+	privacy.Details
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// newMatchResponses builds the caller's view of their matches, loading the
+// other participants' profiles in one query
+func newMatchResponses(userID uint, matches []models.Match) ([]MatchResponse, error) {
+	otherIDs := make([]uint, 0, len(matches))
+	for i := range matches {
+		otherIDs = append(otherIDs, matches[i].OtherUserID(userID))
+	}
+	profiles, err := profileRepo.FindByUserIDs(otherIDs)
+	if err != nil {
+		return nil, err
+	}
+	profileByUser := make(map[uint]*models.Profile, len(profiles))
+	for i := range profiles {
+		profileByUser[profiles[i].UserID] = &profiles[i]
+	}
+	details, err := viewedDetails(userID, profiles)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expiry only applies until the pair exchange a message
+	talking := make(map[uint]bool)
+	if cfg.MatchExpiry > 0 {
+		ids, err := messageRepo.FindCounterpartsWithMessages(userID, otherIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			talking[id] = true
+		}
+	}
+
+	resp := make([]MatchResponse, 0, len(matches))
+	for i := range matches {
+		match := &matches[i]
+		item := MatchResponse{
+			ID:         match.ID,
+			UserID:     match.OtherUserID(userID),
+			Status:     match.Status,
+			Extended:   match.ExtendedAt != nil,
+			CanRespond: match.CanRespond(userID),
+			CreatedAt:  match.CreatedAt,
+			UpdatedAt:  match.UpdatedAt,
+		}
+		if cfg.MatchExpiry > 0 && match.Status == models.MatchAccepted && !talking[item.UserID] {
+			expiresAt := match.ExpiresAt(cfg.MatchExpiry)
+			item.ExpiresAt = &expiresAt
+		}
+		if profile, ok := profileByUser[item.UserID]; ok {
+			item.Details = details[item.UserID]
+			item.DisplayName = profile.DisplayName
+			if len(profile.Photos) > 0 {
+				item.Photo = profile.Photos[0]
+			}
+		}
+		resp = append(resp, item)
+	}
+	return resp, nil
+}
+
+// listMatchesHandler godoc
+// @Summary List matches
+// @Description Get the authenticated user's matches, most recently changed first. By default only pending and accepted matches are returned.
+// @Tags matches
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param status query string false "Only return matches in this status: pending, accepted, declined, unmatched, expired or blocked"
+// @Success 200 {array} MatchResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /matches [get]
+func listMatchesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	statuses := []models.MatchStatus{models.MatchPending, models.MatchAccepted}
+	if v := r.URL.Query().Get("status"); v != "" {
+		switch status := models.MatchStatus(v); status {
+		case models.MatchPending, models.MatchAccepted, models.MatchDeclined, models.MatchUnmatched, models.MatchExpired, models.MatchBlocked:
+			statuses = []models.MatchStatus{status}
+		default:
+			http.Error(w, "Invalid status", http.StatusBadRequest)
+			return
+		}
+	}
+
+	userID := currentUserID(r)
+	matches, err := matchRepo.FindByUserIDAndStatus(userID, statuses...)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := newMatchResponses(userID, matches)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// acceptMatchHandler godoc
+// @Summary Accept a match
+// @Description Accept a pending match. Only the participant who liked first can accept; the match was completed by the other one's like.
+// @Tags matches
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Match ID"
+// @Success 200 {object} MatchResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /matches/{id}/accept [post]
+func acceptMatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	transitionMatch(w, r, models.MatchAccepted)
+}
+
+// declineMatchHandler godoc
+// @Summary Decline a match
+// @Description Decline a pending match. Only the participant who liked first can decline.
+// @Tags matches
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Match ID"
+// @Success 200 {object} MatchResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /matches/{id}/decline [post]
+func declineMatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	transitionMatch(w, r, models.MatchDeclined)
+}
+
+// unmatchHandler godoc
+// @Summary Unmatch
+// @Description End an accepted match. The conversation is hidden from both users, neither can message the other, and they won't be shown to each other in discovery until the re-match cooldown has passed.
+// @Tags matches
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Match ID"
+// @Success 200 {object} MatchResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /matches/{id} [delete]
+func unmatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	transitionMatch(w, r, models.MatchUnmatched)
+}
+
+// extendMatchHandler godoc
+// @Summary Extend a match
+// @Description Restart the expiry window of an accepted match nobody has messaged yet. Each match can be extended once, by either user.
+// @Tags matches
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Match ID"
+// @Success 200 {object} MatchResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /matches/{id}/extend [post]
+func extendMatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if cfg.MatchExpiry == 0 {
+		http.Error(w, "Matches don't expire", http.StatusConflict)
+		return
+	}
+
+	matchID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil || matchID == 0 {
+		http.Error(w, "Invalid match ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := currentUserID(r)
+	match, err := matchRepo.Extend(uint(matchID), userID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, repositories.ErrNotParticipant):
+		http.Error(w, "Match not found", http.StatusNotFound)
+		return
+	case errors.Is(err, repositories.ErrInvalidTransition):
+		http.Error(w, "Only accepted matches can be extended", http.StatusConflict)
+		return
+	case errors.Is(err, repositories.ErrAlreadyExtended):
+		http.Error(w, "Match was already extended", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Failed to extend match", http.StatusInternalServerError)
+		return
+	}
+
+	writeMatch(w, userID, match)
+}
+
+// transitionMatch moves the match in the path to status on behalf of the
+// caller and writes the updated match
+func transitionMatch(w http.ResponseWriter, r *http.Request, status models.MatchStatus) {
+	matchID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil || matchID == 0 {
+		http.Error(w, "Invalid match ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := currentUserID(r)
+	match, err := matchRepo.Transition(uint(matchID), userID, status)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, repositories.ErrNotParticipant):
+		// Other users' matches are reported as missing so IDs can't be probed
+		http.Error(w, "Match not found", http.StatusNotFound)
+		return
+	case errors.Is(err, repositories.ErrAwaitingResponse):
+		http.Error(w, "Waiting for the other user to respond", http.StatusForbidden)
+		return
+	case errors.Is(err, repositories.ErrInvalidTransition):
+		http.Error(w, "Match can't be changed to "+string(status)+" from its current status", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Failed to update match", http.StatusInternalServerError)
+		return
+	}
+	if status == models.MatchAccepted {
+		notifyMatchAccepted(match, userID)
+	}
+
+	writeMatch(w, userID, match)
+}
+
+// writeMatch writes the caller's view of a single match
+func writeMatch(w http.ResponseWriter, userID uint, match *models.Match) {
+	resp, err := newMatchResponses(userID, []models.Match{*match})
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp[0])
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/connectplus/models"
+	"github.com/connectplus/repositories"
+)
+
+const (
+	defaultConversationLimit = 20
+	maxConversationLimit     = 50
+	defaultMessagePageLimit  = 30
+	maxMessagePageLimit      = 100
+)
+
+// MessagePageResponse represents one page of conversation history
+// @swagger:model
+type MessagePageResponse struct {
+	// Messages, newest first
+	Messages []MessageResponse `json:"messages"`
+
+	// Pass as the before parameter to get the next, older page. Omitted on
+	// the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// SendMessageRequest represents the request payload for sending a message
+// @swagger:model
+type SendMessageRequest struct {
+	// required: true
+	// example: Hi there!
+	Content string `json:"content"`
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeMessageCursor returns an opaque cursor pointing at a message. Clients
+// must not rely on its format.
+func encodeMessageCursor(message *models.Message) string {
+	raw := fmt.Sprintf("%d_%d", message.CreatedAt.UnixNano(), message.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeMessageCursor(cursor string) (*repositories.MessageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var nanos int64
+	var id uint
+	if _, err := fmt.Sscanf(string(raw), "%d_%d", &nanos, &id); err != nil {
+		return nil, errInvalidCursor
+	}
+	return &repositories.MessageCursor{CreatedAt: time.Unix(0, nanos), ID: id}, nil
+}
+
+// ConversationResponse represents one entry of the inbox
+// @swagger:model
+type ConversationResponse struct {
+	// The other participant
+	// example: 2
+	UserID uint `json:"user_id"`
+
+	// example: Jane
+	DisplayName string `json:"display_name"`
+
+	// First profile photo of the other participant, if any
+	Photo string `json:"photo,omitempty"`
+
+	// Most recent message in either direction
+	LastMessage MessageResponse `json:"last_message"`
+
+	// Messages from the other participant not yet read by the caller
+	// example: 3
+	UnreadCount int `json:"unread_count"`
+}
+
+// listConversationsHandler godoc
+// @Summary List conversations
+// @Description Get everyone the authenticated user has exchanged messages with, each with the latest message and unread count, most recently active first
+// @Tags messages
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param limit query int false "Maximum number of conversations to return (default 20, max 50)"
+// @Param offset query int false "Number of conversations to skip"
+// @Success 200 {array} ConversationResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /conversations [get]
+func listConversationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, offset, ok := parseLimitOffset(w, r, defaultConversationLimit, maxConversationLimit)
+	if !ok {
+		return
+	}
+
+	summaries, err := messageRepo.ListConversations(currentUserID(r), limit, offset)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	counterpartIDs := make([]uint, 0, len(summaries))
+	for _, summary := range summaries {
+		counterpartIDs = append(counterpartIDs, summary.CounterpartID)
+	}
+	profiles, err := profileRepo.FindByUserIDs(counterpartIDs)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	profileByUser := make(map[uint]*models.Profile, len(profiles))
+	for i := range profiles {
+		profileByUser[profiles[i].UserID] = &profiles[i]
+	}
+
+	resp := make([]ConversationResponse, 0, len(summaries))
+	for _, summary := range summaries {
+		conversation := ConversationResponse{
+			UserID: summary.CounterpartID,
+			LastMessage: MessageResponse{
+				ID:         summary.MessageID,
+				SenderID:   summary.SenderID,
+				ReceiverID: summary.ReceiverID,
+				Content:    summary.Content,
+				IsRead:     summary.IsRead,
+				CreatedAt:  summary.CreatedAt,
+			},
+			UnreadCount: summary.UnreadCount,
+		}
+		if profile, ok := profileByUser[summary.CounterpartID]; ok {
+			conversation.DisplayName = profile.DisplayName
+			if len(profile.Photos) > 0 {
+				conversation.Photo = profile.Photos[0]
+			}
+		}
+		resp = append(resp, conversation)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// messagesHandler routes requests for the messages of one conversation
+func messagesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		listMessagesHandler(w, r)
+	case http.MethodPost:
+		createMessageHandler(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// createMessageHandler godoc
+// @Summary Send a message
+// @Description Send a message to an accepted match. It is also delivered live to both users' open chat connections. Use the chat WebSocket instead where possible.
+// @Tags messages
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param userID path int true "The recipient's user ID"
+// @Param message body SendMessageRequest true "Message"
+// @Success 201 {object} MessageResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /conversations/{userID}/messages [post]
+func createMessageHandler(w http.ResponseWriter, r *http.Request) {
+	otherID, err := strconv.ParseUint(r.PathValue("userID"), 10, 64)
+	if err != nil || otherID == 0 {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req SendMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	message, chatErr := sendMessage(currentUserID(r), uint(otherID), req.Content, "")
+	if chatErr != nil {
+		http.Error(w, chatErr.Message, chatErr.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newMessageResponse(message))
+}
+
+// listMessagesHandler godoc
+// @Summary Get conversation history
+// @Description Get messages exchanged with another user, newest first. Follow next_cursor to page back through older messages; pages stay stable while new messages arrive.
+// @Tags messages
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param userID path int true "The other participant's user ID"
+// @Param before query string false "Cursor from a previous page's next_cursor"
+// @Param limit query int false "Maximum number of messages to return (default 30, max 100)"
+// @Success 200 {object} MessagePageResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /conversations/{userID}/messages [get]
+func listMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	otherID, err := strconv.ParseUint(r.PathValue("userID"), 10, 64)
+	if err != nil || otherID == 0 {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultMessagePageLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxMessagePageLimit)
+	}
+
+	var before *repositories.MessageCursor
+	if v := r.URL.Query().Get("before"); v != "" {
+		before, err = decodeMessageCursor(v)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Fetch one extra message to learn whether an older page exists
+	messages, err := messageRepo.GetConversationPage(currentUserID(r), uint(otherID), before, limit+1)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := MessagePageResponse{Messages: make([]MessageResponse, 0, limit)}
+	if len(messages) > limit {
+		messages = messages[:limit]
+		resp.NextCursor = encodeMessageCursor(&messages[limit-1])
+	}
+	for i := range messages {
+		resp.Messages = append(resp.Messages, newMessageResponse(&messages[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
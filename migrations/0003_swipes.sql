@@ -0,0 +1,21 @@
+-- Add swipes table for like/pass actions
+-- Version: 3.0
+-- Created: 2026-10-18
+
+BEGIN;
+
+-- Swipes record one user's like or pass on another; a user has at most
+-- one swipe per target, later swipes overwrite the direction
+CREATE TABLE swipes (
+    id SERIAL PRIMARY KEY,
+    swiper_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    swiped_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    direction TEXT NOT NULL CHECK (direction IN ('like', 'pass')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_swipes_swiper_swiped ON swipes(swiper_id, swiped_id);
+CREATE INDEX idx_swipes_swiped_id ON swipes(swiped_id);
+
+COMMIT;
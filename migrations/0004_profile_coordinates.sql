@@ -0,0 +1,15 @@
+-- Add coordinates to profiles so match distance can be enforced
+-- Version: 4.0
+-- Created: 2026-10-18
+
+BEGIN;
+
+ALTER TABLE profiles
+    ADD COLUMN latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    ADD COLUMN longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+    ADD COLUMN location_updated_at TIMESTAMP;
+
+-- Radius queries prefilter on a latitude/longitude bounding box
+CREATE INDEX idx_profiles_lat_lng ON profiles(latitude, longitude);
+
+COMMIT;
@@ -0,0 +1,25 @@
+-- Add sessions table for refresh tokens and revocation
+-- Version: 5.0
+-- Created: 2026-10-18
+
+BEGIN;
+
+-- Sessions back each refresh token; only SHA-256 hashes of tokens are stored.
+-- previous_token_hash keeps the last rotated-out token so its reuse can be
+-- detected and the session revoked.
+CREATE TABLE sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    refresh_token_hash TEXT UNIQUE NOT NULL,
+    previous_token_hash TEXT,
+    user_agent TEXT,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX idx_sessions_previous_token_hash ON sessions(previous_token_hash);
+
+COMMIT;
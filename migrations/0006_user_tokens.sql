@@ -0,0 +1,20 @@
+-- Add single-use tokens mailed to users (email verification)
+-- Version: 6.0
+-- Created: 2026-10-18
+
+BEGIN;
+
+-- Only SHA-256 hashes of tokens are stored; used_at makes them single-use
+CREATE TABLE user_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose TEXT NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_user_tokens_user_purpose ON user_tokens(user_id, purpose);
+
+COMMIT;
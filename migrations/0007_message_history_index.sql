@@ -0,0 +1,16 @@
+-- Index messages for keyset pagination of conversation history
+-- Version: 7.0
+-- Created: 2026-10-18
+
+BEGIN;
+
+-- One index per direction so both halves of a conversation query, and the
+-- inbox query, are served in (created_at, id) order
+CREATE INDEX idx_messages_sender_receiver_created ON messages(sender_id, receiver_id, created_at, id);
+CREATE INDEX idx_messages_receiver_sender_created ON messages(receiver_id, sender_id, created_at, id);
+
+-- Both are prefixes of the indexes above
+DROP INDEX IF EXISTS idx_messages_sender_id;
+DROP INDEX IF EXISTS idx_messages_receiver_id;
+
+COMMIT;
@@ -0,0 +1,24 @@
+-- Add an audit trail of match status changes
+-- Version: 8.0
+-- Created: 2026-10-18
+
+BEGIN;
+
+-- actor_id is NULL for changes made by the system; from_status is NULL for
+-- the event that created the match
+CREATE TABLE match_events (
+    id SERIAL PRIMARY KEY,
+    match_id INTEGER NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_match_events_match_id ON match_events(match_id);
+
+-- Matches from mutual likes start pending; initiator_id is the user whose
+-- like completed the pair, and the other participant accepts or declines
+ALTER TABLE matches ADD COLUMN initiator_id INTEGER REFERENCES users(id);
+
+COMMIT;
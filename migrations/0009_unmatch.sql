@@ -0,0 +1,13 @@
+-- Hide conversations of unmatched pairs and track when they unmatched
+-- Version: 9.0
+-- Created: 2026-10-18
+
+BEGIN;
+
+-- Hidden messages are kept for safety review but never shown to the pair
+ALTER TABLE messages ADD COLUMN hidden_at TIMESTAMP;
+
+-- Used to enforce the re-match cooldown
+ALTER TABLE matches ADD COLUMN unmatched_at TIMESTAMP;
+
+COMMIT;
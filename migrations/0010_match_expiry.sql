@@ -0,0 +1,15 @@
+-- Track when matches were accepted and extended so stale ones can expire
+-- Version: 10.0
+-- Created: 2026-10-18
+
+BEGIN;
+
+ALTER TABLE matches ADD COLUMN accepted_at TIMESTAMP;
+
+-- Set when a participant uses the match's one extension
+ALTER TABLE matches ADD COLUMN extended_at TIMESTAMP;
+
+-- Existing accepted matches start their expiry window from their last change
+UPDATE matches SET accepted_at = updated_at WHERE status = 'accepted';
+
+COMMIT;
@@ -0,0 +1,28 @@
+-- Add user roles, report review fields and the admin audit log
+-- Version: 12.0
+-- Created: 2026-10-18
+
+BEGIN;
+
+-- One of 'user', 'moderator' or 'admin'. Users listed in ADMIN_USER_IDS are
+-- made admins at startup.
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user';
+
+ALTER TABLE reports ADD COLUMN reviewer_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE reports ADD COLUMN reviewed_at TIMESTAMP;
+
+CREATE TABLE audit_logs (
+    id SERIAL PRIMARY KEY,
+    actor_id INTEGER NOT NULL REFERENCES users(id),
+    action VARCHAR(50) NOT NULL,
+    target_type VARCHAR(30) NOT NULL,
+    target_id INTEGER NOT NULL,
+    details TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_logs_actor_id ON audit_logs(actor_id);
+CREATE INDEX idx_audit_logs_target ON audit_logs(target_type, target_id);
+CREATE INDEX idx_audit_logs_created_at ON audit_logs(created_at);
+
+COMMIT;
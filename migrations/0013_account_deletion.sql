@@ -0,0 +1,16 @@
+-- Track self-service deactivation and scheduled account deletion
+-- Version: 13.0
+-- Created: 2026-10-18
+
+BEGIN;
+
+-- Set while a user has deactivated their own account, as opposed to being
+-- suspended by a moderator
+ALTER TABLE users ADD COLUMN deactivated_at TIMESTAMP;
+
+-- When the purge job permanently deletes the account
+ALTER TABLE users ADD COLUMN deletion_scheduled_at TIMESTAMP;
+
+CREATE INDEX idx_users_deletion_scheduled_at ON users(deletion_scheduled_at);
+
+COMMIT;
@@ -0,0 +1,25 @@
+-- Add personal data exports
+-- Version: 14.0
+-- Created: 2026-10-18
+
+BEGIN;
+
+CREATE TABLE data_exports (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    -- One of 'pending', 'ready', 'failed' or 'expired'
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    -- Blob store key of the zip archive, cleared once it expires
+    blob_key VARCHAR(255),
+    size_bytes BIGINT,
+    expires_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_data_exports_user_id ON data_exports(user_id);
+CREATE INDEX idx_data_exports_status ON data_exports(status);
+CREATE INDEX idx_data_exports_expires_at ON data_exports(expires_at);
+
+COMMIT;
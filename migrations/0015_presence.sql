@@ -0,0 +1,11 @@
+-- Track when users were last active
+-- Version: 15.0
+-- Created: 2026-10-18
+
+BEGIN;
+
+-- Written in batches by the presence tracker; only matches can see it, and
+-- only if the user allows it
+ALTER TABLE users ADD COLUMN last_active_at TIMESTAMP;
+
+COMMIT;
@@ -0,0 +1,24 @@
+-- Add the in-app notification inbox
+-- Version: 16.0
+-- Created: 2026-10-18
+
+BEGIN;
+
+CREATE TABLE notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    -- One of 'new_match' or 'new_message'
+    kind VARCHAR(30) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    body TEXT,
+    -- JSON object of IDs the app uses to open the right screen
+    data TEXT,
+    -- How many events the entry stands for when a burst was combined
+    count INTEGER NOT NULL DEFAULT 1,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_notifications_user_created ON notifications(user_id, created_at);
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- Support the notification inbox API
+-- Version: 17.0
+-- Created: 2026-10-18
+
+BEGIN;
+
+-- Kinds are now 'new_match', 'new_message', 'like_received' or 'moderation'
+
+-- Unread counts back the app's badge and are fetched with every inbox page
+CREATE INDEX idx_notifications_user_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+COMMIT;
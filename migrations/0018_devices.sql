@@ -0,0 +1,25 @@
+-- Register devices for push notifications
+-- Version: 18.0
+-- Created: 2026-10-18
+
+BEGIN;
+
+CREATE TABLE devices (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    -- One of 'ios', 'android' or 'web'
+    platform VARCHAR(10) NOT NULL,
+    -- APNs device token on iOS, FCM registration token on Android and web.
+    -- A token belongs to whichever user registered it last.
+    token VARCHAR(512) NOT NULL,
+    app_version VARCHAR(50),
+    -- Refreshed every time the app registers the token
+    last_seen_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_devices_user_id ON devices(user_id);
+CREATE UNIQUE INDEX idx_devices_token ON devices(token);
+
+COMMIT;
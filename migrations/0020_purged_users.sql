@@ -0,0 +1,13 @@
+-- Keep deleted accounts as scrubbed placeholders
+-- Version: 20.0
+-- Created: 2026-10-18
+
+BEGIN;
+
+-- Deleting an account scrubs the user row and sets purged_at instead of
+-- removing it. Reports and messages cascade on user deletion, and they,
+-- like the audit log, have to outlive the account while moderators may
+-- still need them.
+ALTER TABLE users ADD COLUMN purged_at TIMESTAMP;
+
+COMMIT;
@@ -0,0 +1,16 @@
+-- Allow one pending data export per user
+-- Version: 21.0
+-- Created: 2026-10-18
+
+BEGIN;
+
+-- Concurrent requests could each find no pending export and start one.
+-- Keep the newest of any such duplicates and fail the rest.
+UPDATE data_exports SET status = 'failed', completed_at = CURRENT_TIMESTAMP
+WHERE status = 'pending' AND id NOT IN (
+    SELECT MAX(id) FROM data_exports WHERE status = 'pending' GROUP BY user_id
+);
+
+CREATE UNIQUE INDEX idx_data_exports_user_pending ON data_exports(user_id) WHERE status = 'pending';
+
+COMMIT;
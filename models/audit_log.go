@@ -0,0 +1,16 @@
+package models
+
+import (
+    "time"
+)
+
+// AuditLog records one action taken through the admin API.
+type AuditLog struct {
+    ID         uint      `gorm:"primaryKey"`
+    ActorID    uint      `gorm:"not null;index"`
+    Action     string    `gorm:"type:varchar(50);not null"`
+    TargetType string    `gorm:"type:varchar(30);not null;index:idx_audit_logs_target"` // e.g. "user", "report" or "match"
+    TargetID   uint      `gorm:"not null;index:idx_audit_logs_target"`
+    Details    string    `gorm:"type:text"`
+    CreatedAt  time.Time `gorm:"autoCreateTime;index"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+    "time"
+)
+
+// Block records that one user blocked another. Blocks apply in both
+// directions: neither user is shown to or can contact the other.
+type Block struct {
+    ID        uint      `gorm:"primaryKey"`
+    BlockerID uint      `gorm:"not null;uniqueIndex:idx_blocks_blocker_blocked"`
+    BlockedID uint      `gorm:"not null;uniqueIndex:idx_blocks_blocker_blocked;index"`
+    CreatedAt time.Time `gorm:"autoCreateTime"`
+}
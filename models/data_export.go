@@ -0,0 +1,36 @@
+package models
+
+import (
+    "time"
+)
+
+type DataExportStatus string
+
+const (
+    DataExportPending DataExportStatus = "pending"
+    DataExportReady   DataExportStatus = "ready"
+    DataExportFailed  DataExportStatus = "failed"
+    // DataExportExpired exports have had their archive deleted.
+    DataExportExpired DataExportStatus = "expired"
+)
+
+// DataExport is an archive of everything stored about a user, built in the
+// background at their request. The archive is kept under BlobKey until
+// ExpiresAt.
+type DataExport struct {
+    ID          uint             `gorm:"primaryKey"`
+    UserID      uint             `gorm:"not null;index;uniqueIndex:idx_data_exports_user_pending,where:status = 'pending'"` // at most one pending export each
+    Status      DataExportStatus `gorm:"type:varchar(20);not null;default:'pending';index"`
+    BlobKey     string           `gorm:"size:255"`
+    SizeBytes   int64
+    ExpiresAt   *time.Time `gorm:"index"`
+    CompletedAt *time.Time
+    CreatedAt   time.Time `gorm:"autoCreateTime"`
+    UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
+
+// IsDownloadable reports whether the archive can be downloaded at the given
+// time.
+func (e *DataExport) IsDownloadable(now time.Time) bool {
+    return e.Status == DataExportReady && e.ExpiresAt != nil && now.Before(*e.ExpiresAt)
+}
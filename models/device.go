@@ -0,0 +1,30 @@
+package models
+
+import (
+    "time"
+)
+
+type DevicePlatform string
+
+const (
+    DeviceIOS     DevicePlatform = "ios"
+    DeviceAndroid DevicePlatform = "android"
+    DeviceWeb     DevicePlatform = "web"
+)
+
+// Device is an app install that can receive push notifications. Token is
+// an APNs device token on iOS and a Firebase Cloud Messaging registration
+// token on Android and the web. A token belongs to at most one user: the
+// latest to register it.
+type Device struct {
+    ID         uint           `gorm:"primaryKey"`
+    UserID     uint           `gorm:"not null;index"`
+    Platform   DevicePlatform `gorm:"type:varchar(10);not null"`
+    Token      string         `gorm:"size:512;not null;uniqueIndex"`
+    AppVersion string         `gorm:"size:50"`
+    // LastSeenAt is when the app last registered the token, which it does
+    // on every launch
+    LastSeenAt time.Time `gorm:"not null"`
+    CreatedAt  time.Time `gorm:"autoCreateTime"`
+    UpdatedAt  time.Time `gorm:"autoUpdateTime"`
+}
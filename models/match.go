@@ -7,16 +7,78 @@ import (
 type MatchStatus string
 
 const (
-    MatchPending  MatchStatus = "pending"
-    MatchAccepted MatchStatus = "accepted"
-    MatchDeclined MatchStatus = "declined"
+    MatchPending   MatchStatus = "pending"
+    MatchAccepted  MatchStatus = "accepted"
+    MatchDeclined  MatchStatus = "declined"
+    MatchUnmatched MatchStatus = "unmatched"
+    // MatchExpired is set by the expiry sweep on accepted matches where
+    // nobody sent a message in time
+    MatchExpired MatchStatus = "expired"
+    // MatchBlocked is set when either user blocks the other
+    MatchBlocked MatchStatus = "blocked"
 )
 
+// matchTransitions lists the statuses a match may move to from each status.
+// Declined, unmatched, expired and blocked are final.
+var matchTransitions = map[MatchStatus][]MatchStatus{
+    MatchPending:  {MatchAccepted, MatchDeclined, MatchBlocked},
+    MatchAccepted: {MatchUnmatched, MatchExpired, MatchBlocked},
+}
+
+// CanTransitionTo reports whether a match in status s may move to next.
+func (s MatchStatus) CanTransitionTo(next MatchStatus) bool {
+    for _, allowed := range matchTransitions[s] {
+        if allowed == next {
+            return true
+        }
+    }
+    return false
+}
+
 type Match struct {
-    ID          uint       `gorm:"primaryKey"`
-    User1ID     uint       `gorm:"not null"`
-    User2ID     uint       `gorm:"not null"`
+    ID          uint        `gorm:"primaryKey"`
+    User1ID     uint        `gorm:"not null;uniqueIndex:idx_matches_user1_user2"`
+    User2ID     uint        `gorm:"not null;uniqueIndex:idx_matches_user1_user2"`
+    // InitiatorID is the user whose like completed the pair. The other
+    // participant accepts or declines the pending match.
+    InitiatorID *uint
     Status      MatchStatus `gorm:"type:varchar(20);default:'pending'"`
-    CreatedAt   time.Time  `gorm:"autoCreateTime"`
-    UpdatedAt   time.Time  `gorm:"autoUpdateTime"`
+    AcceptedAt  *time.Time
+    ExtendedAt  *time.Time // set when a participant used their one extension
+    UnmatchedAt *time.Time
+    CreatedAt   time.Time   `gorm:"autoCreateTime"`
+    UpdatedAt   time.Time   `gorm:"autoUpdateTime"`
+}
+
+// HasParticipant reports whether the user is one of the two matched users.
+func (m *Match) HasParticipant(userID uint) bool {
+    return m.User1ID == userID || m.User2ID == userID
+}
+
+// CanRespond reports whether the user may accept or decline the match: it
+// is pending and they aren't the one who completed the pair.
+func (m *Match) CanRespond(userID uint) bool {
+    return m.Status == MatchPending && m.HasParticipant(userID) &&
+        (m.InitiatorID == nil || *m.InitiatorID != userID)
+}
+
+// OtherUserID returns the participant who isn't userID.
+func (m *Match) OtherUserID(userID uint) uint {
+    if m.User1ID == userID {
+        return m.User2ID
+    }
+    return m.User1ID
+}
+
+// ExpiresAt returns when an accepted match with no messages expires given
+// the expiry window. The window restarts when the match is extended.
+func (m *Match) ExpiresAt(window time.Duration) time.Time {
+    start := m.CreatedAt
+    if m.AcceptedAt != nil {
+        start = *m.AcceptedAt
+    }
+    if m.ExtendedAt != nil {
+        start = *m.ExtendedAt
+    }
+    return start.Add(window)
 }
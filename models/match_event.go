@@ -0,0 +1,16 @@
+package models
+
+import (
+    "time"
+)
+
+// MatchEvent records one status change of a match for auditing. FromStatus
+// is empty for the event that created the match.
+type MatchEvent struct {
+    ID         uint        `gorm:"primaryKey"`
+    MatchID    uint        `gorm:"not null;index"`
+    ActorID    *uint       // nil when the change was made by the system
+    FromStatus MatchStatus `gorm:"type:varchar(20)"`
+    ToStatus   MatchStatus `gorm:"type:varchar(20);not null"`
+    CreatedAt  time.Time   `gorm:"autoCreateTime"`
+}
@@ -4,12 +4,15 @@ import (
     "time"
 )
 
+// Message is a chat message between two users. Hidden messages are left out
+// of every user-facing query but kept for moderators.
 type Message struct {
-    ID         uint      `gorm:"primaryKey"`
-    SenderID   uint      `gorm:"not null"`
-    ReceiverID uint      `gorm:"not null"`
-    Content    string    `gorm:"type:text;not null"`
-    IsRead     bool      `gorm:"default:false"`
-    CreatedAt  time.Time `gorm:"autoCreateTime"`
-    UpdatedAt  time.Time `gorm:"autoUpdateTime"`
+    ID         uint       `gorm:"primaryKey;index:idx_messages_sender_receiver_created,priority:4;index:idx_messages_receiver_sender_created,priority:4"`
+    SenderID   uint       `gorm:"not null;index:idx_messages_sender_receiver_created,priority:1;index:idx_messages_receiver_sender_created,priority:2"`
+    ReceiverID uint       `gorm:"not null;index:idx_messages_sender_receiver_created,priority:2;index:idx_messages_receiver_sender_created,priority:1"`
+    Content    string     `gorm:"type:text;not null"`
+    IsRead     bool       `gorm:"default:false"`
+    HiddenAt   *time.Time // set when the pair unmatched
+    CreatedAt  time.Time  `gorm:"autoCreateTime;index:idx_messages_sender_receiver_created,priority:3;index:idx_messages_receiver_sender_created,priority:3"`
+    UpdatedAt  time.Time  `gorm:"autoUpdateTime"`
 }
@@ -0,0 +1,33 @@
+package models
+
+import (
+    "time"
+)
+
+type NotificationKind string
+
+const (
+    NotificationNewMatch   NotificationKind = "new_match"
+    NotificationNewMessage NotificationKind = "new_message"
+    // NotificationLikeReceived doesn't say who the like came from
+    NotificationLikeReceived NotificationKind = "like_received"
+    // NotificationModeration tells a user about a moderator's action on
+    // their account or on a report they filed
+    NotificationModeration NotificationKind = "moderation"
+)
+
+// Notification is an entry in a user's in-app inbox. Data carries IDs the
+// app needs to open the right screen, such as the match or the sender.
+type Notification struct {
+    ID     uint              `gorm:"primaryKey"`
+    UserID uint              `gorm:"not null;index:idx_notifications_user_created,priority:1;index:idx_notifications_user_unread,where:read_at IS NULL"`
+    Kind   NotificationKind  `gorm:"type:varchar(30);not null"`
+    Title  string            `gorm:"size:255;not null"`
+    Body   string            `gorm:"type:text"`
+    Data   map[string]string `gorm:"serializer:json"`
+    // Count is how many events the entry stands for, when a burst of them
+    // was combined into one
+    Count     int `gorm:"not null;default:1"`
+    ReadAt    *time.Time
+    CreatedAt time.Time `gorm:"autoCreateTime;index:idx_notifications_user_created,priority:2"`
+}
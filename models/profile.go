@@ -5,14 +5,35 @@ import (
 )
 
 type Profile struct {
-    ID          uint      `gorm:"primaryKey"`
-    UserID      uint      `gorm:"uniqueIndex;not null"`
-    DisplayName string    `gorm:"size:100;not null"`
-    Bio         string    `gorm:"size:500"`
-    Gender      string    `gorm:"size:50"`
-    BirthDate   time.Time
-    Location    string    `gorm:"size:100"`
-    Photos      []string  `gorm:"serializer:json"`
-    CreatedAt   time.Time `gorm:"autoCreateTime"`
-    UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+    ID                uint       `gorm:"primaryKey"`
+    UserID            uint       `gorm:"uniqueIndex;not null"`
+    DisplayName       string     `gorm:"size:100;not null"`
+    Bio               string     `gorm:"size:500"`
+    Gender            string     `gorm:"size:50"`
+    BirthDate         time.Time
+    Location          string     `gorm:"size:100"`
+    Latitude          *float64   `gorm:"index:idx_profiles_lat_lng"`
+    Longitude         *float64   `gorm:"index:idx_profiles_lat_lng"`
+    LocationUpdatedAt *time.Time
+    Photos            []string   `gorm:"serializer:json"`
+    CreatedAt         time.Time  `gorm:"autoCreateTime"`
+    UpdatedAt         time.Time  `gorm:"autoUpdateTime"`
+}
+
+// Age returns the profile owner's age in whole years at the given time.
+func (p *Profile) Age(now time.Time) int {
+    if p.BirthDate.IsZero() {
+        return 0
+    }
+    age := now.Year() - p.BirthDate.Year()
+    if now.Month() < p.BirthDate.Month() ||
+        (now.Month() == p.BirthDate.Month() && now.Day() < p.BirthDate.Day()) {
+        age--
+    }
+    return age
+}
+
+// HasCoordinates reports whether the profile has a known position.
+func (p *Profile) HasCoordinates() bool {
+    return p.Latitude != nil && p.Longitude != nil
 }
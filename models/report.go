@@ -0,0 +1,59 @@
+package models
+
+import (
+    "time"
+)
+
+type ReportReason string
+
+const (
+    ReportSpam                 ReportReason = "spam"
+    ReportHarassment           ReportReason = "harassment"
+    ReportInappropriateContent ReportReason = "inappropriate_content"
+    ReportFakeProfile          ReportReason = "fake_profile"
+    ReportUnderage             ReportReason = "underage"
+    ReportOther                ReportReason = "other"
+)
+
+// ReportReasons lists every reason a user can be reported for.
+var ReportReasons = []ReportReason{
+    ReportSpam,
+    ReportHarassment,
+    ReportInappropriateContent,
+    ReportFakeProfile,
+    ReportUnderage,
+    ReportOther,
+}
+
+// Valid reports whether r is one of ReportReasons.
+func (r ReportReason) Valid() bool {
+    for _, reason := range ReportReasons {
+        if reason == r {
+            return true
+        }
+    }
+    return false
+}
+
+type ReportStatus string
+
+const (
+    // ReportOpen reports are waiting in the moderation queue
+    ReportOpen      ReportStatus = "open"
+    ReportResolved  ReportStatus = "resolved"
+    ReportDismissed ReportStatus = "dismissed"
+)
+
+// Report is one user's complaint about another, queued for moderators.
+type Report struct {
+    ID         uint         `gorm:"primaryKey"`
+    ReporterID uint         `gorm:"not null;index"`
+    ReportedID uint         `gorm:"not null;index"`
+    Reason     ReportReason `gorm:"type:varchar(30);not null"`
+    Details    string       `gorm:"type:text"`
+    Status     ReportStatus `gorm:"type:varchar(20);not null;default:'open';index"`
+    ReviewerID *uint        // moderator who resolved or dismissed the report
+    ReviewedAt *time.Time
+    CreatedAt  time.Time    `gorm:"autoCreateTime"`
+    UpdatedAt  time.Time    `gorm:"autoUpdateTime"`
+}
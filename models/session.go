@@ -0,0 +1,24 @@
+package models
+
+import (
+    "time"
+)
+
+// Session is a logged-in device. The refresh token itself is never stored,
+// only its SHA-256 hash.
+type Session struct {
+    ID                uint       `gorm:"primaryKey"`
+    UserID            uint       `gorm:"not null;index"`
+    RefreshTokenHash  string     `gorm:"size:64;uniqueIndex;not null"`
+    PreviousTokenHash string     `gorm:"size:64;index"`
+    UserAgent         string     `gorm:"size:255"`
+    ExpiresAt         time.Time  `gorm:"not null"`
+    RevokedAt         *time.Time
+    CreatedAt         time.Time  `gorm:"autoCreateTime"`
+    UpdatedAt         time.Time  `gorm:"autoUpdateTime"`
+}
+
+// IsActive reports whether the session can still be used at the given time.
+func (s *Session) IsActive(now time.Time) bool {
+    return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
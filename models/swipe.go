@@ -0,0 +1,21 @@
+package models
+
+import (
+    "time"
+)
+
+type SwipeDirection string
+
+const (
+    SwipeLike SwipeDirection = "like"
+    SwipePass SwipeDirection = "pass"
+)
+
+type Swipe struct {
+    ID        uint           `gorm:"primaryKey"`
+    SwiperID  uint           `gorm:"not null;uniqueIndex:idx_swipes_swiper_swiped"`
+    SwipedID  uint           `gorm:"not null;uniqueIndex:idx_swipes_swiper_swiped;index"`
+    Direction SwipeDirection `gorm:"type:varchar(10);not null"`
+    CreatedAt time.Time      `gorm:"autoCreateTime"`
+    UpdatedAt time.Time      `gorm:"autoUpdateTime"`
+}
@@ -4,6 +4,34 @@ import (
     "time"
 )
 
+type Role string
+
+const (
+    RoleUser      Role = "user"
+    RoleModerator Role = "moderator"
+    RoleAdmin     Role = "admin"
+)
+
+// roleRanks orders roles by how much they may do; each role may do
+// everything the roles below it can.
+var roleRanks = map[Role]int{
+    RoleUser:      1,
+    RoleModerator: 2,
+    RoleAdmin:     3,
+}
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+    _, ok := roleRanks[r]
+    return ok
+}
+
+// AtLeast reports whether r grants everything min does. Unknown roles
+// grant nothing.
+func (r Role) AtLeast(min Role) bool {
+    return r.Valid() && roleRanks[r] >= roleRanks[min]
+}
+
 type User struct {
     ID           uint      `gorm:"primaryKey"`
     Email        string    `gorm:"uniqueIndex;not null"`
@@ -11,6 +39,19 @@ type User struct {
     CreatedAt    time.Time `gorm:"autoCreateTime"`
     UpdatedAt    time.Time `gorm:"autoUpdateTime"`
     LastLoginAt  time.Time
+    // LastActiveAt is when the user was last seen, written in batches by the
+    // presence tracker
+    LastActiveAt *time.Time
     IsActive     bool      `gorm:"default:true"`
     IsVerified   bool      `gorm:"default:false"`
+    Role         Role      `gorm:"type:varchar(20);not null;default:'user'"`
+    // DeactivatedAt is set while the user has deactivated their own account,
+    // as opposed to being suspended; logging in again reactivates it
+    DeactivatedAt *time.Time
+    // DeletionScheduledAt is when a deactivated account is purged for good
+    DeletionScheduledAt *time.Time `gorm:"index"`
+    // PurgedAt is when the account was deleted. The row itself stays, scrubbed
+    // of personal data, so that the reports, audit entries and messages kept
+    // for moderators still point at a user
+    PurgedAt *time.Time
 }
@@ -0,0 +1,29 @@
+package models
+
+import (
+    "time"
+)
+
+type TokenPurpose string
+
+const (
+    TokenVerifyEmail   TokenPurpose = "verify_email"
+    TokenResetPassword TokenPurpose = "reset_password"
+)
+
+// UserToken is a single-use token mailed to a user. Only the SHA-256 hash of
+// the token is stored.
+type UserToken struct {
+    ID        uint         `gorm:"primaryKey"`
+    UserID    uint         `gorm:"not null;index:idx_user_tokens_user_purpose"`
+    Purpose   TokenPurpose `gorm:"type:varchar(20);not null;index:idx_user_tokens_user_purpose"`
+    TokenHash string       `gorm:"size:64;uniqueIndex;not null"`
+    ExpiresAt time.Time    `gorm:"not null"`
+    UsedAt    *time.Time
+    CreatedAt time.Time    `gorm:"autoCreateTime"`
+}
+
+// IsUsable reports whether the token can still be redeemed at the given time.
+func (t *UserToken) IsUsable(now time.Time) bool {
+    return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
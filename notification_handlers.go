@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/connectplus/models"
+)
+
+const (
+	defaultNotificationLimit = 20
+	maxNotificationLimit     = 100
+	// maxMarkReadIDs bounds how many notifications one request can mark
+	maxMarkReadIDs = 500
+)
+
+// NotificationResponse represents one entry of the notification inbox
+// @swagger:model
+type NotificationResponse struct {
+	// example: 1
+	ID uint `json:"id"`
+
+	// One of new_match, new_message, like_received or moderation
+	// example: new_match
+	Kind models.NotificationKind `json:"kind"`
+
+	// example: It's a match!
+	Title string `json:"title"`
+
+	// example: You and Jane liked each other. Say hello!
+	Body string `json:"body"`
+
+	// IDs for opening the right screen, such as match_id or sender_id
+	Data map[string]string `json:"data,omitempty"`
+
+	// How many events the entry stands for, such as several messages
+	// example: 1
+	Count int `json:"count"`
+
+	IsRead    bool       `json:"is_read"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NotificationListResponse represents a page of the notification inbox
+// @swagger:model
+type NotificationListResponse struct {
+	// Notifications, newest first
+	Notifications []NotificationResponse `json:"notifications"`
+
+	// Unread notifications in the whole inbox, for the app's badge
+	// example: 3
+	UnreadCount int64 `json:"unread_count"`
+}
+
+// MarkNotificationsReadRequest represents the request payload for marking
+// notifications read. Give either ids or all.
+// @swagger:model
+type MarkNotificationsReadRequest struct {
+	// Notifications to mark read, at most 500
+	IDs []uint `json:"ids"`
+
+	// Mark every notification read
+	All bool `json:"all"`
+}
+
+// MarkNotificationsReadResponse represents the result of marking
+// notifications read
+// @swagger:model
+type MarkNotificationsReadResponse struct {
+	// Notifications that were unread and are now read
+	// example: 2
+	Updated int64 `json:"updated"`
+
+	// Unread notifications left, for the app's badge
+	// example: 1
+	UnreadCount int64 `json:"unread_count"`
+}
+
+func newNotificationResponse(notification *models.Notification) NotificationResponse {
+	return NotificationResponse{
+		ID:        notification.ID,
+		Kind:      notification.Kind,
+		Title:     notification.Title,
+		Body:      notification.Body,
+		Data:      notification.Data,
+		Count:     notification.Count,
+		IsRead:    notification.ReadAt != nil,
+		ReadAt:    notification.ReadAt,
+		CreatedAt: notification.CreatedAt,
+	}
+}
+
+// listNotificationsHandler godoc
+// @Summary List notifications
+// @Description Get the authenticated user's notification inbox, newest first, with the number of unread notifications
+// @Tags notifications
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param limit query int false "Maximum number of notifications to return (default 20, max 100)"
+// @Param offset query int false "Number of notifications to skip"
+// @Success 200 {object} NotificationListResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /notifications [get]
+func listNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, offset, ok := parseLimitOffset(w, r, defaultNotificationLimit, maxNotificationLimit)
+	if !ok {
+		return
+	}
+
+	userID := currentUserID(r)
+	notifications, err := notificationRepo.FindByUserID(userID, limit, offset)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	unread, err := notificationRepo.CountUnread(userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := NotificationListResponse{
+		Notifications: make([]NotificationResponse, 0, len(notifications)),
+		UnreadCount:   unread,
+	}
+	for i := range notifications {
+		resp.Notifications = append(resp.Notifications, newNotificationResponse(&notifications[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// markNotificationsReadHandler godoc
+// @Summary Mark notifications read
+// @Description Mark some or all of the authenticated user's notifications read. IDs of notifications that are already read or aren't the caller's are ignored.
+// @Tags notifications
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param read body MarkNotificationsReadRequest true "Notifications to mark read"
+// @Success 200 {object} MarkNotificationsReadResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /notifications/read [post]
+func markNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req MarkNotificationsReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.All == (len(req.IDs) > 0) {
+		http.Error(w, "Give either ids or all", http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs) > maxMarkReadIDs {
+		http.Error(w, "At most 500 notifications can be marked at once", http.StatusBadRequest)
+		return
+	}
+
+	userID := currentUserID(r)
+	var resp MarkNotificationsReadResponse
+	var err error
+	if req.All {
+		resp.Updated, err = notificationRepo.MarkAllRead(userID)
+	} else {
+		resp.Updated, err = notificationRepo.MarkRead(userID, req.IDs)
+	}
+	if err != nil {
+		http.Error(w, "Failed to update notifications", http.StatusInternalServerError)
+		return
+	}
+	if resp.UnreadCount, err = notificationRepo.CountUnread(userID); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/connectplus/models"
+	"github.com/connectplus/notify"
+)
+
+// notifier tells users about matches, likes, messages and moderation actions
+var notifier *notify.Service
+
+// messagePreviewLength is how many characters of a message are shown in its
+// notification
+const messagePreviewLength = 100
+
+// newNotifier returns the notification service: every notification goes to
+// the in-app inbox and, for users who want them, is pushed to their devices.
+// New matches and moderation notices are also emailed.
+func newNotifier(c Config) *notify.Service {
+	push := &notify.PushChannel{
+		Devices: deviceStore{},
+		Pushers: newPushers(c),
+	}
+	email := &notify.EmailChannel{
+		Mailer:  mail,
+		Address: userEmail,
+		Kinds:   []models.NotificationKind{models.NotificationNewMatch, models.NotificationModeration},
+	}
+	return notify.NewService(inboxChannel{}, notificationPreferences, c.NotificationBatchWindow, push, email)
+}
+
+// inboxChannel stores notifications in the notifications table
+type inboxChannel struct{}
+
+func (inboxChannel) Deliver(ctx context.Context, n notify.Notification) error {
+	return notificationRepo.Create(&models.Notification{
+		UserID: n.UserID,
+		Kind:   n.Kind,
+		Title:  n.Title,
+		Body:   n.Body,
+		Data:   n.Data,
+		Count:  n.Count,
+	})
+}
+
+// deviceStore looks up push tokens in the devices table
+type deviceStore struct{}
+
+func (deviceStore) Devices(userID uint) ([]notify.Device, error) {
+	devices, err := deviceRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]notify.Device, 0, len(devices))
+	for _, device := range devices {
+		result = append(result, notify.Device{Platform: notify.Platform(device.Platform), Token: device.Token})
+	}
+	return result, nil
+}
+
+func (deviceStore) RemoveToken(token string) error {
+	return deviceRepo.DeleteByToken(token)
+}
+
+func notificationPreferences(userID uint) (notify.Preferences, error) {
+	preference, err := findPreferenceOrDefault(userID)
+	if err != nil {
+		return notify.Preferences{}, err
+	}
+	return notify.Preferences{
+		NewMatches: preference.NotifyNewMatches,
+		Messages:   preference.NotifyMessages,
+	}, nil
+}
+
+func userEmail(userID uint) (string, error) {
+	user, err := userRepo.FindByID(userID)
+	if err != nil {
+		return "", err
+	}
+	return user.Email, nil
+}
+
+// displayName returns the name a user shows on their profile
+func displayName(userID uint) string {
+	profile, err := profileRepo.FindByUserID(userID)
+	if err != nil || profile.DisplayName == "" {
+		return "Someone"
+	}
+	return profile.DisplayName
+}
+
+// notifyNewMatch tells the user who liked first that likerID liked them
+// back, so they can accept the pending match
+func notifyNewMatch(match *models.Match, likerID uint) {
+	notifier.Notify(notify.Notification{
+		UserID: match.OtherUserID(likerID),
+		Kind:   models.NotificationNewMatch,
+		Title:  "It's a match!",
+		Body:   fmt.Sprintf("%s liked you back. Accept the match to start chatting.", displayName(likerID)),
+		Data:   map[string]string{"match_id": strconv.FormatUint(uint64(match.ID), 10)},
+	})
+}
+
+// notifyMatchAccepted tells the user who completed a match that the other
+// user accepted it
+func notifyMatchAccepted(match *models.Match, accepterID uint) {
+	notifier.Notify(notify.Notification{
+		UserID: match.OtherUserID(accepterID),
+		Kind:   models.NotificationNewMatch,
+		Title:  "Match accepted",
+		Body:   fmt.Sprintf("You and %s can now chat. Say hello!", displayName(accepterID)),
+		Data:   map[string]string{"match_id": strconv.FormatUint(uint64(match.ID), 10)},
+	})
+}
+
+// notifyNewMessage tells the receiver about a message. Bursts from the same
+// sender are combined into one notification.
+func notifyNewMessage(message *models.Message) {
+	sender := strconv.FormatUint(uint64(message.SenderID), 10)
+	notifier.Notify(notify.Notification{
+		UserID:    message.ReceiverID,
+		Kind:      models.NotificationNewMessage,
+		Title:     displayName(message.SenderID),
+		Body:      messagePreview(message.Content),
+		Data:      map[string]string{"sender_id": sender, "message_id": strconv.FormatUint(uint64(message.ID), 10)},
+		GroupKey:  "messages:" + sender,
+		GroupBody: "%d new messages",
+	})
+}
+
+// notifyLikeReceived tells a user someone liked them, without saying who so
+// that liking someone doesn't reveal the user's own swipes
+func notifyLikeReceived(userID uint) {
+	notifier.Notify(notify.Notification{
+		UserID:    userID,
+		Kind:      models.NotificationLikeReceived,
+		Title:     "Someone likes you",
+		Body:      "Keep swiping to find out who.",
+		GroupKey:  "likes",
+		GroupBody: "%d people like you. Keep swiping to find out who.",
+	})
+}
+
+// notifyReportReviewed tells the user who filed a report that a moderator
+// has closed it
+func notifyReportReviewed(report *models.Report) {
+	body := "Thanks for letting us know. We've taken action."
+	if report.Status == models.ReportDismissed {
+		body = "Thanks for letting us know. We didn't find a breach of our community guidelines."
+	}
+	notifier.Notify(notify.Notification{
+		UserID: report.ReporterID,
+		Kind:   models.NotificationModeration,
+		Title:  "Your report has been reviewed",
+		Body:   body,
+		Data:   map[string]string{"report_id": strconv.FormatUint(uint64(report.ID), 10)},
+	})
+}
+
+// notifyAccountSuspended tells a user a moderator suspended their account.
+// They can't sign in to see the inbox, so it reaches them by email.
+func notifyAccountSuspended(userID uint) {
+	notifier.Notify(notify.Notification{
+		UserID: userID,
+		Kind:   models.NotificationModeration,
+		Title:  "Your account has been suspended",
+		Body:   "A moderator suspended your account for breaching our community guidelines. Contact support if you think this is a mistake.",
+	})
+}
+
+// notifyAccountReactivated tells a user their suspension was lifted
+func notifyAccountReactivated(userID uint) {
+	notifier.Notify(notify.Notification{
+		UserID: userID,
+		Kind:   models.NotificationModeration,
+		Title:  "Your account has been reinstated",
+		Body:   "A moderator lifted your suspension. You can sign in again.",
+	})
+}
+
+func messagePreview(content string) string {
+	if utf8.RuneCountInString(content) <= messagePreviewLength {
+		return content
+	}
+	runes := []rune(content)
+	return string(runes[:messagePreviewLength-1]) + "…"
+}
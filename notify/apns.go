@@ -0,0 +1,148 @@
+package notify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// apnsTokenLifetime is how long a provider token is reused. Apple rejects
+// tokens older than an hour and throttles refreshes more often than every
+// 20 minutes.
+const apnsTokenLifetime = 50 * time.Minute
+
+// APNsPusher sends notifications to iOS devices through the Apple Push
+// Notification service, authenticating with a token-based (.p8) key.
+type APNsPusher struct {
+	// Endpoint is https://api.push.apple.com, or
+	// https://api.sandbox.push.apple.com for development builds.
+	Endpoint string
+	// Topic is the app's bundle ID.
+	Topic  string
+	KeyID  string
+	TeamID string
+	Key    *ecdsa.PrivateKey
+	Client *http.Client
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+	now      func() time.Time
+}
+
+// ParseAPNsKey reads an APNs auth key (.p8 file).
+func ParseAPNsKey(pemKey []byte) (*ecdsa.PrivateKey, error) {
+	key, err := parsePKCS8Key(string(pemKey))
+	if err != nil {
+		return nil, err
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("APNs key is not an ECDSA key")
+	}
+	return ecKey, nil
+}
+
+type apnsAlert struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type apnsAps struct {
+	Alert    apnsAlert `json:"alert"`
+	Sound    string    `json:"sound"`
+	ThreadID string    `json:"thread-id,omitempty"`
+}
+
+func (p *APNsPusher) Push(ctx context.Context, token string, n Notification) error {
+	providerToken, err := p.providerToken()
+	if err != nil {
+		return fmt.Errorf("apns provider token: %w", err)
+	}
+
+	// Custom data sits beside "aps" at the top level of the payload
+	payload := map[string]any{
+		"aps": apnsAps{
+			Alert:    apnsAlert{Title: n.Title, Body: n.Body},
+			Sound:    "default",
+			ThreadID: n.GroupKey,
+		},
+	}
+	for k, v := range pushData(n) {
+		payload[k] = v
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{
+		"Authorization":  {"bearer " + providerToken},
+		"Apns-Topic":     {p.Topic},
+		"Apns-Push-Type": {"alert"},
+	}
+	// A newer notification in the same group replaces the older one on the
+	// lock screen
+	if n.GroupKey != "" && len(n.GroupKey) <= 64 {
+		header.Set("Apns-Collapse-Id", n.GroupKey)
+	}
+	url := strings.TrimRight(p.Endpoint, "/") + "/3/device/" + url.PathEscape(token)
+	status, respBody, err := postJSON(ctx, p.Client, url, header, body)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusOK {
+		return nil
+	}
+
+	var reason struct {
+		Reason string `json:"reason"`
+	}
+	json.Unmarshal(respBody, &reason)
+	switch {
+	case status == http.StatusGone,
+		reason.Reason == "BadDeviceToken",
+		reason.Reason == "Unregistered",
+		reason.Reason == "DeviceTokenNotForTopic":
+		return ErrInvalidToken
+	default:
+		return fmt.Errorf("apns: %d %s", status, reason.Reason)
+	}
+}
+
+// providerToken returns the signed JWT APNs authenticates requests with,
+// reusing it for apnsTokenLifetime
+func (p *APNsPusher) providerToken() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now
+	if p.now != nil {
+		now = p.now
+	}
+	t := now()
+	if p.token != "" && t.Sub(p.issuedAt) < apnsTokenLifetime {
+		return p.token, nil
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": p.TeamID,
+		"iat": t.Unix(),
+	})
+	token.Header["kid"] = p.KeyID
+	signed, err := token.SignedString(p.Key)
+	if err != nil {
+		return "", err
+	}
+	p.token = signed
+	p.issuedAt = t
+	return signed, nil
+}
@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/connectplus/mailer"
+	"github.com/connectplus/models"
+)
+
+// EmailChannel emails notifications to users.
+type EmailChannel struct {
+	Mailer mailer.Mailer
+	// Address returns the user's email address.
+	Address func(userID uint) (string, error)
+	// Kinds limits which notifications are emailed; nil emails every kind.
+	Kinds []models.NotificationKind
+}
+
+func (c *EmailChannel) Deliver(ctx context.Context, n Notification) error {
+	if c.Kinds != nil && !containsKind(c.Kinds, n.Kind) {
+		return nil
+	}
+	to, err := c.Address(n.UserID)
+	if err != nil {
+		return err
+	}
+	return c.Mailer.Send(ctx, mailer.Message{
+		To:      to,
+		Subject: n.Title,
+		Body:    n.Body + "\n\nOpen Connect+ to see more. You can turn these emails off in the app's notification settings.",
+	})
+}
+
+func containsKind(kinds []models.NotificationKind, kind models.NotificationKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,242 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	defaultFCMEndpoint = "https://fcm.googleapis.com"
+	fcmScope           = "https://www.googleapis.com/auth/firebase.messaging"
+)
+
+// FCMPusher sends notifications through the Firebase Cloud Messaging HTTP v1
+// API, which serves Android and web tokens (and iOS ones registered through
+// Firebase).
+type FCMPusher struct {
+	// Endpoint defaults to https://fcm.googleapis.com.
+	Endpoint  string
+	ProjectID string
+	// AccessToken returns an OAuth 2.0 token with the Firebase Messaging
+	// scope, such as GoogleServiceAccount.AccessToken.
+	AccessToken func(ctx context.Context) (string, error)
+	Client      *http.Client
+}
+
+type fcmRequest struct {
+	Message fcmMessage `json:"message"`
+}
+
+type fcmMessage struct {
+	Token        string            `json:"token"`
+	Notification fcmNotification   `json:"notification"`
+	Data         map[string]string `json:"data"`
+	Android      *fcmAndroid       `json:"android,omitempty"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type fcmAndroid struct {
+	// CollapseKey makes a newer notification replace an older one with the
+	// same key that hasn't been shown yet
+	CollapseKey string `json:"collapse_key,omitempty"`
+}
+
+type fcmError struct {
+	Error struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		// Details mixes FcmError entries, which carry ErrorCode, with
+		// google.rpc.BadRequest ones, which name the invalid fields
+		Details []struct {
+			ErrorCode       string `json:"errorCode"`
+			FieldViolations []struct {
+				Field string `json:"field"`
+			} `json:"fieldViolations"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+func (p *FCMPusher) Push(ctx context.Context, token string, n Notification) error {
+	accessToken, err := p.AccessToken(ctx)
+	if err != nil {
+		return fmt.Errorf("fcm access token: %w", err)
+	}
+
+	msg := fcmMessage{
+		Token:        token,
+		Notification: fcmNotification{Title: n.Title, Body: n.Body},
+		Data:         pushData(n),
+	}
+	if n.GroupKey != "" {
+		msg.Android = &fcmAndroid{CollapseKey: n.GroupKey}
+	}
+	body, err := json.Marshal(fcmRequest{Message: msg})
+	if err != nil {
+		return err
+	}
+
+	endpoint := p.Endpoint
+	if endpoint == "" {
+		endpoint = defaultFCMEndpoint
+	}
+	url := fmt.Sprintf("%s/v1/projects/%s/messages:send", strings.TrimRight(endpoint, "/"), url.PathEscape(p.ProjectID))
+	header := http.Header{"Authorization": {"Bearer " + accessToken}}
+	status, respBody, err := postJSON(ctx, p.Client, url, header, body)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusOK {
+		return nil
+	}
+
+	var fe fcmError
+	json.Unmarshal(respBody, &fe)
+	if fe.invalidToken() {
+		return ErrInvalidToken
+	}
+	return fmt.Errorf("fcm: %d %s: %s", status, fe.Error.Status, fe.Error.Message)
+}
+
+// invalidToken reports whether FCM rejected the registration token itself:
+// either it was unregistered, or the request was invalid because of the token
+// field. Other failures, including a 404 for a wrong project, leave the
+// token alone.
+func (fe *fcmError) invalidToken() bool {
+	invalidArgument := false
+	tokenViolation := false
+	for _, detail := range fe.Error.Details {
+		switch detail.ErrorCode {
+		case "UNREGISTERED":
+			return true
+		case "INVALID_ARGUMENT":
+			invalidArgument = true
+		}
+		for _, violation := range detail.FieldViolations {
+			if violation.Field == "message.token" {
+				tokenViolation = true
+			}
+		}
+	}
+	return invalidArgument && tokenViolation
+}
+
+// GoogleServiceAccount issues OAuth 2.0 access tokens for a Google service
+// account, as downloaded from the Firebase console.
+type GoogleServiceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+
+	Client *http.Client
+
+	key       *rsa.PrivateKey
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+	now       func() time.Time
+}
+
+// ParseGoogleServiceAccount reads a service account key file.
+func ParseGoogleServiceAccount(credentials []byte) (*GoogleServiceAccount, error) {
+	var a GoogleServiceAccount
+	if err := json.Unmarshal(credentials, &a); err != nil {
+		return nil, err
+	}
+	if a.ClientEmail == "" || a.TokenURI == "" {
+		return nil, errors.New("service account is missing client_email or token_uri")
+	}
+	key, err := parsePKCS8Key(a.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("service account key is not an RSA key")
+	}
+	a.key = rsaKey
+	a.now = time.Now
+	return &a, nil
+}
+
+// AccessToken returns a token for the Firebase Messaging scope, reusing the
+// previous one until shortly before it expires.
+func (a *GoogleServiceAccount) AccessToken(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	if a.token != "" && now.Before(a.expiresAt.Add(-time.Minute)) {
+		return a.token, nil
+	}
+
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   a.ClientEmail,
+		"scope": fcmScope,
+		"aud":   a.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(a.key)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.TokenURI, bytes.NewBufferString(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token exchange: %s", resp.Status)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	a.token = token.AccessToken
+	a.expiresAt = now.Add(time.Duration(token.ExpiresIn) * time.Second)
+	return a.token, nil
+}
+
+// parsePKCS8Key decodes a PEM "PRIVATE KEY" block, the format both Google
+// service account keys and APNs auth keys come in
+func parsePKCS8Key(pemKey string) (any, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	return x509.ParsePKCS8PrivateKey(block.Bytes)
+}
@@ -0,0 +1,172 @@
+// Package notify tells users about things that happen while they aren't
+// looking, such as new matches and messages, through an in-app inbox, push
+// notifications and email.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/connectplus/models"
+)
+
+// Notification is one thing to tell a user about.
+type Notification struct {
+	UserID uint
+	Kind   models.NotificationKind
+	Title  string
+	Body   string
+	// Data is passed to the app so it can open the right screen, for
+	// example {"match_id": "12"}.
+	Data map[string]string
+
+	// GroupKey batches bursts: once a notification has been delivered,
+	// others for the same user with the same GroupKey are held for the
+	// batch window and then delivered as one.
+	GroupKey string
+	// GroupBody replaces Body when several notifications were combined, with
+	// %d for how many.
+	GroupBody string
+	// Count is how many notifications this one stands for.
+	Count int
+}
+
+// Channel delivers notifications one way, such as push or email.
+type Channel interface {
+	Deliver(ctx context.Context, n Notification) error
+}
+
+// Preferences are which notifications a user wants outside the app.
+type Preferences struct {
+	// NewMatches also covers likes, which may become matches
+	NewMatches bool
+	Messages   bool
+}
+
+// Allows reports whether the user wants notifications of the given kind.
+func (p Preferences) Allows(kind models.NotificationKind) bool {
+	switch kind {
+	case models.NotificationNewMatch, models.NotificationLikeReceived:
+		return p.NewMatches
+	case models.NotificationNewMessage:
+		return p.Messages
+	default:
+		return true
+	}
+}
+
+// PreferenceSource looks up a user's notification preferences.
+type PreferenceSource func(userID uint) (Preferences, error)
+
+type batchKey struct {
+	userID uint
+	group  string
+}
+
+type batch struct {
+	held   int
+	latest Notification
+}
+
+// Service delivers notifications to the inbox and, if the user's
+// preferences allow, to every other channel. Delivery happens in the
+// background, so Notify never blocks the request that triggered it.
+type Service struct {
+	inbox       Channel
+	channels    []Channel
+	preferences PreferenceSource
+	window      time.Duration
+	// timeout bounds each delivery to every channel
+	timeout time.Duration
+
+	mu      sync.Mutex
+	batches map[batchKey]*batch
+}
+
+// NewService returns a service that records every notification in inbox and
+// sends those the user's preferences allow through channels. Bursts with
+// the same GroupKey are batched over window; zero disables batching.
+func NewService(inbox Channel, preferences PreferenceSource, window time.Duration, channels ...Channel) *Service {
+	return &Service{
+		inbox:       inbox,
+		channels:    channels,
+		preferences: preferences,
+		window:      window,
+		timeout:     30 * time.Second,
+		batches:     make(map[batchKey]*batch),
+	}
+}
+
+// Notify delivers n, unless it belongs to a burst already being batched.
+func (s *Service) Notify(n Notification) {
+	if n.Count == 0 {
+		n.Count = 1
+	}
+
+	if n.GroupKey != "" && s.window > 0 {
+		key := batchKey{n.UserID, n.GroupKey}
+		s.mu.Lock()
+		if b, ok := s.batches[key]; ok {
+			b.held += n.Count
+			b.latest = n
+			s.mu.Unlock()
+			return
+		}
+		s.batches[key] = &batch{}
+		s.mu.Unlock()
+		time.AfterFunc(s.window, func() { s.flush(key) })
+	}
+
+	go s.deliver(n)
+}
+
+// flush delivers what was held for a batch at the end of its window. The
+// window then starts again, so a steady stream of messages yields one
+// notification per window rather than one per message.
+func (s *Service) flush(key batchKey) {
+	s.mu.Lock()
+	b := s.batches[key]
+	if b.held == 0 {
+		delete(s.batches, key)
+		s.mu.Unlock()
+		return
+	}
+	n := b.latest
+	n.Count = b.held
+	b.held = 0
+	s.mu.Unlock()
+	time.AfterFunc(s.window, func() { s.flush(key) })
+
+	if n.Count > 1 && n.GroupBody != "" {
+		n.Body = fmt.Sprintf(n.GroupBody, n.Count)
+	}
+	s.deliver(n)
+}
+
+func (s *Service) deliver(n Notification) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	if s.inbox != nil {
+		if err := s.inbox.Deliver(ctx, n); err != nil {
+			log.Printf("Failed to add %s notification to user %d's inbox: %v", n.Kind, n.UserID, err)
+		}
+	}
+
+	prefs, err := s.preferences(n.UserID)
+	if err != nil {
+		log.Printf("Failed to load notification preferences of user %d: %v", n.UserID, err)
+		return
+	}
+	if !prefs.Allows(n.Kind) {
+		return
+	}
+	for _, channel := range s.channels {
+		if err := channel.Deliver(ctx, n); err != nil {
+			log.Printf("Failed to deliver %s notification to user %d: %v", n.Kind, n.UserID, err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/connectplus/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a channel that remembers what it was given.
+type recorder struct {
+	mu        sync.Mutex
+	delivered []Notification
+	signal    chan struct{}
+}
+
+func newRecorder() *recorder {
+	return &recorder{signal: make(chan struct{}, 100)}
+}
+
+func (r *recorder) Deliver(ctx context.Context, n Notification) error {
+	r.mu.Lock()
+	r.delivered = append(r.delivered, n)
+	r.mu.Unlock()
+	r.signal <- struct{}{}
+	return nil
+}
+
+// wait blocks until count more notifications have been delivered.
+func (r *recorder) wait(t *testing.T, count int) {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		select {
+		case <-r.signal:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for notification %d of %d", i+1, count)
+		}
+	}
+}
+
+func (r *recorder) all() []Notification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Notification(nil), r.delivered...)
+}
+
+func allow(prefs Preferences) PreferenceSource {
+	return func(uint) (Preferences, error) { return prefs, nil }
+}
+
+func TestNotifyRespectsPreferences(t *testing.T) {
+	inbox, push := newRecorder(), newRecorder()
+	s := NewService(inbox, allow(Preferences{NewMatches: false, Messages: true}), 0, push)
+
+	s.Notify(Notification{UserID: 1, Kind: models.NotificationNewMatch, Title: "New match"})
+	inbox.wait(t, 1)
+	s.Notify(Notification{UserID: 1, Kind: models.NotificationNewMessage, Title: "New message"})
+	inbox.wait(t, 1)
+	push.wait(t, 1)
+
+	assert.Len(t, inbox.all(), 2, "the inbox gets every notification")
+	pushed := push.all()
+	require.Len(t, pushed, 1)
+	assert.Equal(t, models.NotificationNewMessage, pushed[0].Kind)
+	assert.Equal(t, 1, pushed[0].Count)
+}
+
+func TestPreferencesAllows(t *testing.T) {
+	prefs := Preferences{NewMatches: false, Messages: true}
+	assert.False(t, prefs.Allows(models.NotificationNewMatch))
+	assert.False(t, prefs.Allows(models.NotificationLikeReceived))
+	assert.True(t, prefs.Allows(models.NotificationNewMessage))
+	assert.True(t, prefs.Allows(models.NotificationModeration), "moderation notices can't be turned off")
+}
+
+func TestNotifyBatchesBursts(t *testing.T) {
+	inbox, push := newRecorder(), newRecorder()
+	s := NewService(inbox, allow(Preferences{Messages: true}), 50*time.Millisecond, push)
+
+	message := func(body string) Notification {
+		return Notification{
+			UserID:    1,
+			Kind:      models.NotificationNewMessage,
+			Body:      body,
+			GroupKey:  "messages:2",
+			GroupBody: "%d new messages",
+		}
+	}
+	s.Notify(message("one"))
+	push.wait(t, 1)
+	s.Notify(message("two"))
+	s.Notify(message("three"))
+	push.wait(t, 1)
+
+	pushed := push.all()
+	require.Len(t, pushed, 2)
+	assert.Equal(t, "one", pushed[0].Body)
+	assert.Equal(t, 2, pushed[1].Count)
+	assert.Equal(t, "2 new messages", pushed[1].Body)
+
+	// Other users and groups aren't held back by the batch
+	s.Notify(Notification{UserID: 3, Kind: models.NotificationNewMessage, Body: "hi", GroupKey: "messages:2"})
+	push.wait(t, 1)
+	assert.Equal(t, uint(3), push.all()[2].UserID)
+}
+
+func TestBatchWithOneHeldNotificationKeepsItsBody(t *testing.T) {
+	push := newRecorder()
+	s := NewService(nil, allow(Preferences{Messages: true}), 20*time.Millisecond, push)
+
+	n := Notification{UserID: 1, Kind: models.NotificationNewMessage, GroupKey: "g", GroupBody: "%d new messages"}
+	n.Body = "first"
+	s.Notify(n)
+	n.Body = "second"
+	s.Notify(n)
+	push.wait(t, 2)
+
+	assert.Equal(t, "second", push.all()[1].Body)
+}
+
+// fakeDevices is an in-memory DeviceStore.
+type fakeDevices struct {
+	devices []Device
+	removed []string
+}
+
+func (f *fakeDevices) Devices(userID uint) ([]Device, error) { return f.devices, nil }
+
+func (f *fakeDevices) RemoveToken(token string) error {
+	f.removed = append(f.removed, token)
+	return nil
+}
+
+type pusherFunc func(ctx context.Context, token string, n Notification) error
+
+func (f pusherFunc) Push(ctx context.Context, token string, n Notification) error {
+	return f(ctx, token, n)
+}
+
+func TestPushChannelRemovesInvalidTokens(t *testing.T) {
+	devices := &fakeDevices{devices: []Device{
+		{Platform: PlatformIOS, Token: "good"},
+		{Platform: PlatformAndroid, Token: "stale"},
+		{Platform: "unknown", Token: "skipped"},
+	}}
+	var pushedTo []string
+	pusher := pusherFunc(func(ctx context.Context, token string, n Notification) error {
+		pushedTo = append(pushedTo, token)
+		if token == "stale" {
+			return ErrInvalidToken
+		}
+		return nil
+	})
+	c := &PushChannel{Devices: devices, Pushers: map[Platform]Pusher{
+		PlatformIOS:     pusher,
+		PlatformAndroid: pusher,
+	}}
+
+	assert.NoError(t, c.Deliver(context.Background(), Notification{UserID: 1}))
+	assert.Equal(t, []string{"good", "stale"}, pushedTo)
+	assert.Equal(t, []string{"stale"}, devices.removed)
+}
@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Platform is the kind of device a push token belongs to.
+type Platform string
+
+const (
+	PlatformIOS     Platform = "ios"
+	PlatformAndroid Platform = "android"
+	PlatformWeb     Platform = "web"
+)
+
+// Device is a push token registered by one of a user's devices.
+type Device struct {
+	Platform Platform
+	Token    string
+}
+
+// DeviceStore looks up where to push a user's notifications.
+type DeviceStore interface {
+	Devices(userID uint) ([]Device, error)
+	// RemoveToken forgets a token the push provider reported invalid.
+	RemoveToken(token string) error
+}
+
+// ErrInvalidToken is returned by a Pusher when the provider says a token
+// will never work again, for example because the app was uninstalled.
+var ErrInvalidToken = errors.New("push token is no longer valid")
+
+// Pusher sends a notification to one device through a push provider.
+type Pusher interface {
+	Push(ctx context.Context, token string, n Notification) error
+}
+
+// PushChannel pushes notifications to every device a user has registered,
+// using the pusher for each device's platform. Tokens the provider rejects
+// as invalid are removed from the store.
+type PushChannel struct {
+	Devices DeviceStore
+	Pushers map[Platform]Pusher
+}
+
+func (c *PushChannel) Deliver(ctx context.Context, n Notification) error {
+	devices, err := c.Devices.Devices(n.UserID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, device := range devices {
+		pusher, ok := c.Pushers[device.Platform]
+		if !ok {
+			continue
+		}
+		err := pusher.Push(ctx, device.Token, n)
+		if errors.Is(err, ErrInvalidToken) {
+			err = c.Devices.RemoveToken(device.Token)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s device: %w", device.Platform, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// pushData is the custom data sent with a push, which the app reads to
+// decide which screen to open
+func pushData(n Notification) map[string]string {
+	data := make(map[string]string, len(n.Data)+2)
+	for k, v := range n.Data {
+		data[k] = v
+	}
+	data["kind"] = string(n.Kind)
+	data["count"] = strconv.Itoa(n.Count)
+	return data
+}
+
+// postJSON sends a JSON request to a push provider and returns the status
+// and at most 4 KB of the response body
+func postJSON(ctx context.Context, client *http.Client, url string, header http.Header, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return resp.StatusCode, respBody, err
+}
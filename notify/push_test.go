@@ -0,0 +1,179 @@
+package notify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/connectplus/models"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pkcs8PEM(t *testing.T, key any) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+var testNotification = Notification{
+	UserID:   1,
+	Kind:     models.NotificationNewMatch,
+	Title:    "It's a match!",
+	Body:     "You and Sam liked each other.",
+	Data:     map[string]string{"match_id": "12"},
+	GroupKey: "match:12",
+	Count:    1,
+}
+
+func TestFCMPush(t *testing.T) {
+	var got fcmRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/projects/demo/messages:send", r.URL.Path)
+		assert.Equal(t, "Bearer access", r.Header.Get("Authorization"))
+		json.NewDecoder(r.Body).Decode(&got)
+		if got.Message.Token == "stale" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`))
+			return
+		}
+		if got.Message.Token == "malformed" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"status":"INVALID_ARGUMENT","details":[` +
+				`{"@type":"type.googleapis.com/google.firebase.fcm.v1.FcmError","errorCode":"INVALID_ARGUMENT"},` +
+				`{"@type":"type.googleapis.com/google.rpc.BadRequest","fieldViolations":[{"field":"message.token"}]}]}}`))
+			return
+		}
+		if got.Message.Token == "bad-payload" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"status":"INVALID_ARGUMENT","details":[` +
+				`{"@type":"type.googleapis.com/google.firebase.fcm.v1.FcmError","errorCode":"INVALID_ARGUMENT"},` +
+				`{"@type":"type.googleapis.com/google.rpc.BadRequest","fieldViolations":[{"field":"message.data"}]}]}}`))
+			return
+		}
+		if got.Message.Token == "wrong-project" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"status":"NOT_FOUND","message":"Requested entity was not found."}}`))
+			return
+		}
+		if got.Message.Token == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"name":"projects/demo/messages/1"}`))
+	}))
+	defer server.Close()
+
+	p := &FCMPusher{
+		Endpoint:    server.URL,
+		ProjectID:   "demo",
+		AccessToken: func(context.Context) (string, error) { return "access", nil },
+	}
+
+	require.NoError(t, p.Push(context.Background(), "token", testNotification))
+	assert.Equal(t, "token", got.Message.Token)
+	assert.Equal(t, "It's a match!", got.Message.Notification.Title)
+	assert.Equal(t, "12", got.Message.Data["match_id"])
+	assert.Equal(t, "new_match", got.Message.Data["kind"])
+
+	assert.ErrorIs(t, p.Push(context.Background(), "stale", testNotification), ErrInvalidToken)
+	assert.ErrorIs(t, p.Push(context.Background(), "malformed", testNotification), ErrInvalidToken)
+	for _, token := range []string{"bad-payload", "wrong-project", "broken"} {
+		err := p.Push(context.Background(), token, testNotification)
+		assert.Error(t, err, token)
+		assert.NotErrorIs(t, err, ErrInvalidToken, token)
+	}
+}
+
+func TestGoogleServiceAccountAccessToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	exchanges := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		exchanges++
+		require.NoError(t, r.ParseForm())
+		assertion, err := jwt.Parse(r.PostForm.Get("assertion"), func(*jwt.Token) (any, error) {
+			return &key.PublicKey, nil
+		})
+		require.NoError(t, err)
+		claims := assertion.Claims.(jwt.MapClaims)
+		assert.Equal(t, "push@demo.iam.gserviceaccount.com", claims["iss"])
+		assert.Equal(t, fcmScope, claims["scope"])
+		w.Write([]byte(`{"access_token":"access","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	credentials, _ := json.Marshal(map[string]string{
+		"project_id":   "demo",
+		"client_email": "push@demo.iam.gserviceaccount.com",
+		"private_key":  string(pkcs8PEM(t, key)),
+		"token_uri":    server.URL,
+	})
+	account, err := ParseGoogleServiceAccount(credentials)
+	require.NoError(t, err)
+	assert.Equal(t, "demo", account.ProjectID)
+
+	for i := 0; i < 2; i++ {
+		token, err := account.AccessToken(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "access", token)
+	}
+	assert.Equal(t, 1, exchanges, "the token is reused until it expires")
+}
+
+func TestAPNsPush(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	parsed, err := ParseAPNsKey(pkcs8PEM(t, key))
+	require.NoError(t, err)
+
+	var payload map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := jwt.Parse(strings.TrimPrefix(r.Header.Get("Authorization"), "bearer "), func(*jwt.Token) (any, error) {
+			return &key.PublicKey, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "KEY123", token.Header["kid"])
+		assert.Equal(t, "com.connectplus.app", r.Header.Get("apns-topic"))
+		assert.Equal(t, "match:12", r.Header.Get("apns-collapse-id"))
+		json.NewDecoder(r.Body).Decode(&payload)
+
+		switch strings.TrimPrefix(r.URL.Path, "/3/device/") {
+		case "gone":
+			w.WriteHeader(http.StatusGone)
+			w.Write([]byte(`{"reason":"Unregistered"}`))
+		case "bad":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"reason":"BadDeviceToken"}`))
+		case "throttled":
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"reason":"TooManyRequests"}`))
+		}
+	}))
+	defer server.Close()
+
+	p := &APNsPusher{Endpoint: server.URL, Topic: "com.connectplus.app", KeyID: "KEY123", TeamID: "TEAM", Key: parsed}
+
+	require.NoError(t, p.Push(context.Background(), "token", testNotification))
+	aps := payload["aps"].(map[string]any)
+	assert.Equal(t, "It's a match!", aps["alert"].(map[string]any)["title"])
+	assert.Equal(t, "12", payload["match_id"])
+
+	assert.ErrorIs(t, p.Push(context.Background(), "gone", testNotification), ErrInvalidToken)
+	assert.ErrorIs(t, p.Push(context.Background(), "bad", testNotification), ErrInvalidToken)
+	err = p.Push(context.Background(), "throttled", testNotification)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidToken)
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/connectplus/mailer"
+	"github.com/connectplus/models"
+	"gorm.io/gorm"
+)
+
+const passwordResetTokenTTL = time.Hour
+
+// ForgotPasswordRequest represents the request payload for starting a password reset
+// @swagger:model
+type ForgotPasswordRequest struct {
+	// Email of the account to reset
+	// required: true
+	// example: john@example.com
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest represents the request payload for completing a password reset
+// @swagger:model
+type ResetPasswordRequest struct {
+	// Reset token from the email link
+	// required: true
+	Token string `json:"token"`
+
+	// New password
+	// required: true
+	// minLength: 8
+	NewPassword string `json:"new_password"`
+}
+
+// ChangePasswordRequest represents the request payload for changing a password
+// @swagger:model
+type ChangePasswordRequest struct {
+	// Current password
+	// required: true
+	CurrentPassword string `json:"current_password"`
+
+	// New password
+	// required: true
+	// minLength: 8
+	NewPassword string `json:"new_password"`
+}
+
+// sendPasswordResetEmail mails the user a new password reset link
+func sendPasswordResetEmail(ctx context.Context, user *models.User) error {
+	token, err := issueUserToken(user.ID, models.TokenResetPassword, passwordResetTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", cfg.WebAppURL, url.QueryEscape(token))
+	ctx, cancel := context.WithTimeout(ctx, mailTimeout)
+	defer cancel()
+	return mail.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Connect+ password",
+		Body: fmt.Sprintf("We received a request to reset your Connect+ password.\n\nChoose a new password here:\n\n%s\n\n"+
+			"The link expires in 1 hour and can be used once. If you didn't ask to reset your password, you can ignore this email.", link),
+	})
+}
+
+// forgotPasswordHandler godoc
+// @Summary Request a password reset
+// @Description Email a single-use password reset link. Always succeeds so it can't be used to discover which emails are registered.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body ForgotPasswordRequest true "Account email"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /auth/password/forgot [post]
+func forgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Email) == "" {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	// Look up and mail in the background so the response time doesn't
+	// reveal whether the account exists
+	email := strings.TrimSpace(req.Email)
+	go func() {
+		user, err := userRepo.FindByEmail(email)
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("Failed to look up user for password reset: %v", err)
+			}
+			return
+		}
+
+		allowed, err := canMailToken(user.ID, models.TokenResetPassword)
+		if err != nil {
+			log.Printf("Failed to check password reset throttle for user %d: %v", user.ID, err)
+			return
+		}
+		if !allowed {
+			return
+		}
+
+		if err := sendPasswordResetEmail(context.Background(), user); err != nil {
+			log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "If an account exists for that email, a reset link has been sent",
+	})
+}
+
+// resetPasswordHandler godoc
+// @Summary Reset password
+// @Description Set a new password using a reset link token. All existing sessions are logged out.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/password/reset [post]
+func resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	// Validate before redeeming so a weak password doesn't burn the token
+	if len(req.NewPassword) < minPasswordLength {
+		http.Error(w, "Password must be at least 8 characters", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := redeemSignedUserToken(req.Token, models.TokenResetPassword)
+	if errors.Is(err, errInvalidUserToken) {
+		http.Error(w, "Invalid or expired reset link", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := setPassword(userID, req.NewPassword); err != nil {
+		http.Error(w, "Failed to update password", http.StatusInternalServerError)
+		return
+	}
+
+	// Whoever had the old password may still hold a session
+	if err := sessionRepo.RevokeAllForUser(userID); err != nil {
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Password reset successfully",
+	})
+}
+
+// changePasswordHandler godoc
+// @Summary Change password
+// @Description Change the authenticated user's password. Other sessions are logged out; the current one stays valid.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param request body ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /user/password [put]
+func changePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.NewPassword) < minPasswordLength {
+		http.Error(w, "Password must be at least 8 characters", http.StatusBadRequest)
+		return
+	}
+
+	userID := currentUserID(r)
+	user, err := userRepo.FindByID(userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !checkPassword(user.PasswordHash, req.CurrentPassword) {
+		http.Error(w, "Current password is incorrect", http.StatusForbidden)
+		return
+	}
+
+	if err := setPassword(userID, req.NewPassword); err != nil {
+		http.Error(w, "Failed to update password", http.StatusInternalServerError)
+		return
+	}
+	if err := sessionRepo.RevokeOthersForUser(userID, currentSessionID(r)); err != nil {
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Password changed successfully",
+	})
+}
+
+// setPassword hashes and stores a new password, invalidating any reset
+// links still outstanding
+func setPassword(userID uint, password string) error {
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	result := db.Model(&models.User{}).Where("id = ?", userID).Update("password_hash", hashedPassword)
+	if result.Error != nil {
+		return result.Error
+	}
+	return userTokenRepo.InvalidateForUser(userID, models.TokenResetPassword)
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/connectplus/models"
+	"github.com/connectplus/photos"
+	"github.com/connectplus/storage"
+	"gorm.io/gorm"
+)
+
+const maxProfilePhotos = 6
+
+// PhotoResponse represents one profile photo
+// @swagger:model
+type PhotoResponse struct {
+	// example: 9f86d081884c7d65
+	ID string `json:"id"`
+
+	// Full-size photo, at most 1080px on the longest side
+	URL string `json:"url"`
+
+	// Thumbnail, at most 320px on the longest side
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+// ReorderPhotosRequest represents the request payload for reordering profile photos
+// @swagger:model
+type ReorderPhotosRequest struct {
+	// Every photo ID of the profile, in the new display order
+	// required: true
+	PhotoIDs []string `json:"photo_ids"`
+}
+
+// Blob keys are derived from the owner and a random photo ID, so the key of
+// any photo can be recovered from its URL in Profile.Photos.
+func photoKey(userID uint, photoID string) string {
+	return fmt.Sprintf("photos/%d/%s.jpg", userID, photoID)
+}
+
+func thumbnailKey(userID uint, photoID string) string {
+	return fmt.Sprintf("photos/%d/%s_thumb.jpg", userID, photoID)
+}
+
+func photoIDFromURL(url string) string {
+	return strings.TrimSuffix(path.Base(url), ".jpg")
+}
+
+func newPhotoID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func newPhotoResponses(profile *models.Profile) []PhotoResponse {
+	resp := make([]PhotoResponse, 0, len(profile.Photos))
+	for _, url := range profile.Photos {
+		id := photoIDFromURL(url)
+		resp = append(resp, PhotoResponse{
+			ID:           id,
+			URL:          url,
+			ThumbnailURL: blobs.URL(thumbnailKey(profile.UserID, id)),
+		})
+	}
+	return resp
+}
+
+// deletePhotoBlobs removes both renditions of a photo, logging failures
+// since the profile no longer references them either way
+func deletePhotoBlobs(ctx context.Context, userID uint, photoID string) {
+	for _, key := range []string{photoKey(userID, photoID), thumbnailKey(userID, photoID)} {
+		if err := blobs.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete blob %s: %v", key, err)
+		}
+	}
+}
+
+var (
+	errTooManyPhotos = errors.New("too many photos")
+	errPhotoOrder    = errors.New("photo IDs don't match the profile's photos")
+	errPhotoNotFound = errors.New("photo not found")
+)
+
+// writePhotoUpdateError writes the response for a failed change to the
+// caller's photo list
+func writePhotoUpdateError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errTooManyPhotos):
+		http.Error(w, "A profile can have at most 6 photos", http.StatusConflict)
+	case errors.Is(err, errPhotoOrder):
+		http.Error(w, "Photo IDs must list every photo exactly once", http.StatusBadRequest)
+	case errors.Is(err, errPhotoNotFound):
+		http.Error(w, "Photo not found", http.StatusNotFound)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Profile not found", http.StatusNotFound)
+	default:
+		http.Error(w, "Failed to update profile", http.StatusInternalServerError)
+	}
+}
+
+// findOwnProfile loads the caller's profile, writing the error response and
+// returning nil if it can't
+func findOwnProfile(w http.ResponseWriter, r *http.Request) *models.Profile {
+	profile, err := profileRepo.FindByUserID(currentUserID(r))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Profile not found", http.StatusNotFound)
+			return nil
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil
+	}
+	return profile
+}
+
+// photosHandler routes requests for the caller's photo list
+func photosHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		listPhotosHandler(w, r)
+	case http.MethodPost:
+		uploadPhotoHandler(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// listPhotosHandler godoc
+// @Summary List profile photos
+// @Description List the authenticated user's photos in display order
+// @Tags profiles
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {array} PhotoResponse
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /profile/photos [get]
+func listPhotosHandler(w http.ResponseWriter, r *http.Request) {
+	profile := findOwnProfile(w, r)
+	if profile == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newPhotoResponses(profile))
+}
+
+// uploadPhotoHandler godoc
+// @Summary Upload a profile photo
+// @Description Upload a JPEG or PNG of at most 10 MB. Metadata such as GPS position is removed and a thumbnail is generated. The photo is added at the end of the profile's photos.
+// @Tags profiles
+// @Accept  multipart/form-data
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param photo formData file true "Image file"
+// @Success 201 {array} PhotoResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /profile/photos [post]
+func uploadPhotoHandler(w http.ResponseWriter, r *http.Request) {
+	// Leave room for the multipart framing around the file itself
+	r.Body = http.MaxBytesReader(w, r.Body, photos.MaxUploadBytes+64<<10)
+	file, _, err := r.FormFile("photo")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, "Photo must be at most 10 MB", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Missing photo file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	profile := findOwnProfile(w, r)
+	if profile == nil {
+		return
+	}
+	if len(profile.Photos) >= maxProfilePhotos {
+		http.Error(w, "A profile can have at most 6 photos", http.StatusConflict)
+		return
+	}
+
+	processed, err := photos.Process(file)
+	if err != nil {
+		switch {
+		case errors.Is(err, photos.ErrTooLarge):
+			http.Error(w, "Photo must be at most 10 MB", http.StatusRequestEntityTooLarge)
+		case errors.Is(err, photos.ErrUnsupportedType), errors.Is(err, photos.ErrTooManyPixels):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to process photo", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	photoID, err := newPhotoID()
+	if err != nil {
+		http.Error(w, "Failed to store photo", http.StatusInternalServerError)
+		return
+	}
+
+	ctx := r.Context()
+	if err := blobs.Put(ctx, photoKey(profile.UserID, photoID), bytes.NewReader(processed.Full), "image/jpeg"); err != nil {
+		log.Printf("Failed to store photo for user %d: %v", profile.UserID, err)
+		http.Error(w, "Failed to store photo", http.StatusInternalServerError)
+		return
+	}
+	if err := blobs.Put(ctx, thumbnailKey(profile.UserID, photoID), bytes.NewReader(processed.Thumbnail), "image/jpeg"); err != nil {
+		log.Printf("Failed to store thumbnail for user %d: %v", profile.UserID, err)
+		deletePhotoBlobs(context.Background(), profile.UserID, photoID)
+		http.Error(w, "Failed to store photo", http.StatusInternalServerError)
+		return
+	}
+
+	// Other uploads may have finished while this one was processed, so the
+	// cap is checked again against the locked profile
+	url := blobs.URL(photoKey(profile.UserID, photoID))
+	profile, err = profileRepo.UpdatePhotos(profile.UserID, func(current []string) ([]string, error) {
+		if len(current) >= maxProfilePhotos {
+			return nil, errTooManyPhotos
+		}
+		return append(current, url), nil
+	})
+	if err != nil {
+		deletePhotoBlobs(context.Background(), currentUserID(r), photoID)
+		writePhotoUpdateError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newPhotoResponses(profile))
+}
+
+// reorderPhotosHandler godoc
+// @Summary Reorder profile photos
+// @Description Set the display order of the authenticated user's photos. The request must list every photo exactly once.
+// @Tags profiles
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param order body ReorderPhotosRequest true "Photo IDs in display order"
+// @Success 200 {array} PhotoResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /profile/photos/order [put]
+func reorderPhotosHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ReorderPhotosRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := profileRepo.UpdatePhotos(currentUserID(r), func(current []string) ([]string, error) {
+		urls := make(map[string]string, len(current))
+		for _, url := range current {
+			urls[photoIDFromURL(url)] = url
+		}
+		if len(req.PhotoIDs) != len(urls) {
+			return nil, errPhotoOrder
+		}
+		ordered := make([]string, 0, len(req.PhotoIDs))
+		for _, id := range req.PhotoIDs {
+			url, ok := urls[id]
+			if !ok {
+				return nil, errPhotoOrder
+			}
+			ordered = append(ordered, url)
+			delete(urls, id)
+		}
+		return ordered, nil
+	})
+	if err != nil {
+		writePhotoUpdateError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newPhotoResponses(profile))
+}
+
+// deletePhotoHandler godoc
+// @Summary Delete a profile photo
+// @Description Remove a photo from the authenticated user's profile and delete its files
+// @Tags profiles
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param photoID path string true "Photo ID"
+// @Success 200 {array} PhotoResponse
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /profile/photos/{photoID} [delete]
+func deletePhotoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	photoID := r.PathValue("photoID")
+	profile, err := profileRepo.UpdatePhotos(currentUserID(r), func(current []string) ([]string, error) {
+		remaining := make([]string, 0, len(current))
+		for _, url := range current {
+			if photoIDFromURL(url) != photoID {
+				remaining = append(remaining, url)
+			}
+		}
+		if len(remaining) == len(current) {
+			return nil, errPhotoNotFound
+		}
+		return remaining, nil
+	})
+	if err != nil {
+		writePhotoUpdateError(w, err)
+		return
+	}
+	deletePhotoBlobs(r.Context(), profile.UserID, photoID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newPhotoResponses(profile))
+}
+
+// uploadsHandler serves photos kept by a storage.LocalStore. Directory
+// listings are refused so photo IDs can't be enumerated, and other blobs such
+// as data exports are never served publicly.
+func uploadsHandler(store *storage.LocalStore) http.Handler {
+	files := http.StripPrefix("/uploads/", http.FileServer(http.Dir(store.Dir)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/uploads/photos/") || strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,97 @@
+// Package photos validates uploaded profile photos and prepares them for
+// storage: images are decoded, rotated upright, stripped of all metadata
+// (including EXIF GPS coordinates) by re-encoding, and resized.
+package photos
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+)
+
+const (
+	// MaxUploadBytes is the largest file accepted for upload.
+	MaxUploadBytes = 10 << 20
+	// MaxPixels guards against decompression bombs: small files that decode
+	// to enormous images.
+	MaxPixels = 40_000_000
+
+	// FullSize and ThumbnailSize bound the longest side of each rendition.
+	FullSize      = 1080
+	ThumbnailSize = 320
+
+	jpegQuality = 85
+)
+
+var (
+	ErrTooLarge        = errors.New("image exceeds the maximum upload size")
+	ErrUnsupportedType = errors.New("image must be a JPEG or PNG")
+	ErrTooManyPixels   = errors.New("image dimensions are too large")
+)
+
+// Processed holds the JPEG renditions of an uploaded photo.
+type Processed struct {
+	Full      []byte
+	Thumbnail []byte
+}
+
+// Process reads an uploaded image and returns metadata-free JPEG renditions.
+func Process(r io.Reader) (*Processed, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxUploadBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxUploadBytes {
+		return nil, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if contentType != "image/jpeg" && contentType != "image/png" {
+		return nil, ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	var img image.Image
+	if contentType == "image/jpeg" {
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if err == nil {
+			img = applyOrientation(img, exifOrientation(data))
+		}
+	} else {
+		img, err = png.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+
+	full, err := encode(Resize(img, FullSize))
+	if err != nil {
+		return nil, err
+	}
+	thumb, err := encode(Resize(img, ThumbnailSize))
+	if err != nil {
+		return nil, err
+	}
+	return &Processed{Full: full, Thumbnail: thumb}, nil
+}
+
+// encode writes img as a JPEG. The standard library encoder writes no EXIF,
+// XMP or ICC segments, which is what strips the original's metadata.
+func encode(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,135 @@
+package photos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	return img
+}
+
+// withExif inserts an APP1 Exif segment carrying the given orientation and
+// a fake GPS payload right after the JPEG's SOI marker.
+func withExif(t *testing.T, jpg []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1) // one IFD entry
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0) // padding and no next IFD
+	tiff = append(tiff, []byte("GPSLatitude=37.7749")...)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	seg := []byte{0xFF, 0xE1}
+	seg = binary.BigEndian.AppendUint16(seg, uint16(len(payload)+2))
+	seg = append(seg, payload...)
+
+	require.True(t, bytes.HasPrefix(jpg, []byte{0xFF, 0xD8}))
+	out := append([]byte{0xFF, 0xD8}, seg...)
+	return append(out, jpg[2:]...)
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	return buf.Bytes()
+}
+
+func TestProcessStripsMetadataAndAppliesOrientation(t *testing.T) {
+	src := withExif(t, encodeJPEG(t, testImage(200, 100)), 6)
+	assert.Equal(t, 6, exifOrientation(src))
+
+	out, err := Process(bytes.NewReader(src))
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(out.Full), "Exif")
+	assert.NotContains(t, string(out.Full), "GPS")
+	assert.NotContains(t, string(out.Thumbnail), "GPS")
+
+	// Rotated 90 degrees, so the landscape source comes out portrait
+	config, err := jpeg.DecodeConfig(bytes.NewReader(out.Full))
+	require.NoError(t, err)
+	assert.Equal(t, 100, config.Width)
+	assert.Equal(t, 200, config.Height)
+}
+
+func TestProcessResizes(t *testing.T) {
+	src := encodeJPEG(t, testImage(2000, 1000))
+
+	out, err := Process(bytes.NewReader(src))
+	require.NoError(t, err)
+
+	full, err := jpeg.DecodeConfig(bytes.NewReader(out.Full))
+	require.NoError(t, err)
+	assert.Equal(t, FullSize, full.Width)
+	assert.Equal(t, FullSize/2, full.Height)
+
+	thumb, err := jpeg.DecodeConfig(bytes.NewReader(out.Thumbnail))
+	require.NoError(t, err)
+	assert.Equal(t, ThumbnailSize, thumb.Width)
+	assert.Equal(t, ThumbnailSize/2, thumb.Height)
+}
+
+func TestProcessAcceptsPNG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, testImage(50, 80)))
+
+	out, err := Process(&buf)
+	require.NoError(t, err)
+
+	config, err := jpeg.DecodeConfig(bytes.NewReader(out.Full))
+	require.NoError(t, err)
+	assert.Equal(t, 50, config.Width)
+	assert.Equal(t, 80, config.Height)
+}
+
+func TestProcessRejectsUnsupportedTypes(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, gif.Encode(&buf, testImage(10, 10), nil))
+
+	_, err := Process(&buf)
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+
+	_, err = Process(bytes.NewReader([]byte("not an image at all")))
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+}
+
+func TestProcessRejectsOversizedUploads(t *testing.T) {
+	data := make([]byte, MaxUploadBytes+1)
+	copy(data, encodeJPEG(t, testImage(10, 10)))
+
+	_, err := Process(bytes.NewReader(data))
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestApplyOrientation(t *testing.T) {
+	img := testImage(3, 2)
+	marker := color.RGBA{255, 0, 0, 255}
+	img.Set(0, 0, marker)
+
+	// Rotating 90 clockwise moves the top-left pixel to the top-right
+	rotated := applyOrientation(img, 6)
+	assert.Equal(t, image.Rect(0, 0, 2, 3), rotated.Bounds())
+	assert.Equal(t, marker, rotated.At(1, 0))
+
+	// Rotating 180 moves it to the bottom-right
+	rotated = applyOrientation(img, 3)
+	assert.Equal(t, marker, rotated.At(2, 1))
+}
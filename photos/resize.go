@@ -0,0 +1,49 @@
+package photos
+
+import (
+	"image"
+	"image/color"
+)
+
+// Resize scales img down so its longest side is at most maxSide, averaging
+// each source area into its destination pixel. Images already small enough
+// are copied unscaled.
+func Resize(img image.Image, maxSide int) *image.RGBA {
+	b := img.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+	dstW, dstH := srcW, srcH
+	if srcW >= srcH && srcW > maxSide {
+		dstW, dstH = maxSide, max(1, srcH*maxSide/srcW)
+	} else if srcH > srcW && srcH > maxSide {
+		dstW, dstH = max(1, srcW*maxSide/srcH), maxSide
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := b.Min.Y + y*srcH/dstH
+		y1 := max(y0+1, b.Min.Y+(y+1)*srcH/dstH)
+		for x := 0; x < dstW; x++ {
+			x0 := b.Min.X + x*srcW/dstW
+			x1 := max(x0+1, b.Min.X+(x+1)*srcW/dstW)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					bl += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/connectplus/models"
+	"gorm.io/gorm"
+)
+
+const (
+	minPreferenceAge   = 18
+	maxPreferenceAge   = 99
+	maxMatchDistanceKm = 500
+)
+
+// PreferenceRequest represents the request payload for updating the caller's
+// preferences. Omitted fields are left unchanged.
+// @swagger:model
+type PreferenceRequest struct {
+	// Maximum distance to discovered users in kilometers
+	// minimum: 1
+	// maximum: 500
+	// example: 50
+	MatchDistance *int `json:"match_distance"`
+
+	// minimum: 18
+	// example: 25
+	MinAge *int `json:"min_age"`
+
+	// maximum: 99
+	// example: 35
+	MaxAge *int `json:"max_age"`
+
+	NotifyNewMatches *bool `json:"notify_new_matches"`
+	NotifyMessages   *bool `json:"notify_messages"`
+
+	// Whether other users can see if the caller is online
+	ShowOnlineStatus *bool `json:"show_online_status"`
+	// Whether other users can see when the caller was last active
+	ShowLastActive *bool `json:"show_last_active"`
+	// Whether other users can see how far away the caller is
+	ShowDistance *bool `json:"show_distance"`
+}
+
+// PreferenceResponse represents the caller's discovery, notification and
+// privacy preferences
+// @swagger:model
+type PreferenceResponse struct {
+	// example: 50
+	MatchDistance int `json:"match_distance"`
+	// example: 25
+	MinAge int `json:"min_age"`
+	// example: 35
+	MaxAge int `json:"max_age"`
+
+	NotifyNewMatches bool `json:"notify_new_matches"`
+	NotifyMessages   bool `json:"notify_messages"`
+
+	ShowOnlineStatus bool `json:"show_online_status"`
+	ShowLastActive   bool `json:"show_last_active"`
+	ShowDistance     bool `json:"show_distance"`
+}
+
+func newPreferenceResponse(preference *models.Preference) PreferenceResponse {
+	return PreferenceResponse{
+		MatchDistance:    preference.MatchDistance,
+		MinAge:           preference.MinAge,
+		MaxAge:           preference.MaxAge,
+		NotifyNewMatches: preference.NotifyNewMatches,
+		NotifyMessages:   preference.NotifyMessages,
+		ShowOnlineStatus: preference.ShowOnlineStatus,
+		ShowLastActive:   preference.ShowLastActive,
+		ShowDistance:     preference.ShowDistance,
+	}
+}
+
+// defaultPreference returns the preferences of a user who has never saved
+// any, matching the model defaults
+func defaultPreference(userID uint) *models.Preference {
+	return &models.Preference{
+		UserID:           userID,
+		MatchDistance:    50,
+		MinAge:           minPreferenceAge,
+		MaxAge:           maxPreferenceAge,
+		NotifyNewMatches: true,
+		NotifyMessages:   true,
+		ShowOnlineStatus: true,
+		ShowLastActive:   true,
+		ShowDistance:     true,
+	}
+}
+
+// findPreferenceOrDefault returns the user's stored preferences, or the
+// defaults if they have never saved any
+func findPreferenceOrDefault(userID uint) (*models.Preference, error) {
+	preference, err := preferenceRepo.FindByUserID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return defaultPreference(userID), nil
+	}
+	return preference, err
+}
+
+// apply validates the supplied fields and copies them onto preference,
+// returning a message describing the first invalid field
+func (req *PreferenceRequest) apply(preference *models.Preference) string {
+	if req.MatchDistance != nil {
+		if *req.MatchDistance < 1 || *req.MatchDistance > maxMatchDistanceKm {
+			return "Match distance must be between 1 and 500 km"
+		}
+		preference.MatchDistance = *req.MatchDistance
+	}
+	if req.MinAge != nil {
+		preference.MinAge = *req.MinAge
+	}
+	if req.MaxAge != nil {
+		preference.MaxAge = *req.MaxAge
+	}
+	if preference.MinAge < minPreferenceAge || preference.MaxAge > maxPreferenceAge || preference.MinAge > preference.MaxAge {
+		return "Age range must be within 18 to 99, with the minimum no higher than the maximum"
+	}
+
+	for _, flag := range []struct {
+		value *bool
+		dest  *bool
+	}{
+		{req.NotifyNewMatches, &preference.NotifyNewMatches},
+		{req.NotifyMessages, &preference.NotifyMessages},
+		{req.ShowOnlineStatus, &preference.ShowOnlineStatus},
+		{req.ShowLastActive, &preference.ShowLastActive},
+		{req.ShowDistance, &preference.ShowDistance},
+	} {
+		if flag.value != nil {
+			*flag.dest = *flag.value
+		}
+	}
+	return ""
+}
+
+// preferencesHandler routes requests for the caller's preferences
+func preferencesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		getPreferencesHandler(w, r)
+	case http.MethodPut:
+		updatePreferencesHandler(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// getPreferencesHandler godoc
+// @Summary Get preferences
+// @Description Get the authenticated user's discovery, notification and privacy preferences, or the defaults if they have never saved any
+// @Tags preferences
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} PreferenceResponse
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /preferences [get]
+func getPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	preference, err := findPreferenceOrDefault(currentUserID(r))
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newPreferenceResponse(preference))
+}
+
+// updatePreferencesHandler godoc
+// @Summary Update preferences
+// @Description Update the authenticated user's preferences. Omitted fields are left unchanged. The show_* settings control what other users see on the caller's profile.
+// @Tags preferences
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param preferences body PreferenceRequest true "Preferences to change"
+// @Success 200 {object} PreferenceResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /preferences [put]
+func updatePreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	var req PreferenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	userID := currentUserID(r)
+	preference, err := preferenceRepo.FindByUserID(userID)
+	isNew := errors.Is(err, gorm.ErrRecordNotFound)
+	if isNew {
+		preference = defaultPreference(userID)
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if errMsg := req.apply(preference); errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	// Create skips false values in favour of the column defaults, so a new
+	// row is created first and then saved in full
+	if isNew {
+		created := defaultPreference(userID)
+		if err := preferenceRepo.Create(created); err != nil {
+			http.Error(w, "Failed to save preferences", http.StatusInternalServerError)
+			return
+		}
+		preference.ID = created.ID
+	}
+	if err := preferenceRepo.Update(preference); err != nil {
+		http.Error(w, "Failed to save preferences", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newPreferenceResponse(preference))
+}
@@ -0,0 +1,148 @@
+// Package presence tracks which users are online and when they were last
+// active.
+package presence
+
+import (
+	"sync"
+	"time"
+)
+
+// Presence is what is known about a user's activity.
+type Presence struct {
+	// Online is set while the user has a live connection or has been active
+	// within the store's online window
+	Online bool
+	// LastActiveAt is when the user was last seen; zero if never
+	LastActiveAt time.Time
+}
+
+// Store tracks presence. Memory keeps it in this process; a shared backend
+// lets several API instances agree on who is online.
+type Store interface {
+	// Connect records a live connection by the user, such as a chat
+	// WebSocket; Disconnect ends one. A user with any open connection is
+	// online.
+	Connect(userID uint)
+	Disconnect(userID uint)
+	// Touch records activity by the user, such as an authenticated request.
+	Touch(userID uint)
+	// Get returns the user's presence. ok is false if the store has no
+	// record of them, in which case their last-active time must be read
+	// from where Drain's results were persisted.
+	Get(userID uint) (p Presence, ok bool)
+	// Drain returns the last-active time of every user active since the
+	// previous call, so they can be persisted in one batch however often
+	// each user was active.
+	Drain() map[uint]time.Time
+	// Restore hands back times from Drain that couldn't be persisted, so
+	// the next Drain returns them again. A user's newer activity since the
+	// Drain is kept.
+	Restore(lastActive map[uint]time.Time)
+}
+
+type memoryEntry struct {
+	connections  int
+	lastActiveAt time.Time
+	dirty        bool
+}
+
+// Memory is a Store kept in this process's memory.
+type Memory struct {
+	mu      sync.Mutex
+	window  time.Duration
+	entries map[uint]*memoryEntry
+	now     func() time.Time
+}
+
+// NewMemory returns an empty store in which users count as online for window
+// after their last activity, as well as while connected.
+func NewMemory(window time.Duration) *Memory {
+	return &Memory{window: window, entries: make(map[uint]*memoryEntry), now: time.Now}
+}
+
+// entry returns the user's entry, creating it if needed. The caller must
+// hold m.mu.
+func (m *Memory) entry(userID uint) *memoryEntry {
+	e, ok := m.entries[userID]
+	if !ok {
+		e = &memoryEntry{}
+		m.entries[userID] = e
+	}
+	return e
+}
+
+func (m *Memory) Connect(userID uint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.entry(userID)
+	e.connections++
+	e.lastActiveAt = m.now()
+	e.dirty = true
+}
+
+// Disconnect counts as activity too, so a user who just closed the app shows
+// as last active then rather than when they connected.
+func (m *Memory) Disconnect(userID uint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[userID]
+	if !ok || e.connections == 0 {
+		return
+	}
+	e.connections--
+	e.lastActiveAt = m.now()
+	e.dirty = true
+}
+
+func (m *Memory) Touch(userID uint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.entry(userID)
+	e.lastActiveAt = m.now()
+	e.dirty = true
+}
+
+func (m *Memory) Get(userID uint) (Presence, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[userID]
+	if !ok {
+		return Presence{}, false
+	}
+	return Presence{
+		Online:       e.connections > 0 || m.now().Sub(e.lastActiveAt) < m.window,
+		LastActiveAt: e.lastActiveAt,
+	}, true
+}
+
+// Drain also forgets users who are offline and whose last-active time has
+// already been drained, so the store only holds recently active users.
+func (m *Memory) Drain() map[uint]time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	drained := make(map[uint]time.Time)
+	for userID, e := range m.entries {
+		if e.dirty {
+			drained[userID] = e.lastActiveAt
+			e.dirty = false
+			continue
+		}
+		if e.connections == 0 && now.Sub(e.lastActiveAt) >= m.window {
+			delete(m.entries, userID)
+		}
+	}
+	return drained
+}
+
+func (m *Memory) Restore(lastActive map[uint]time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for userID, t := range lastActive {
+		e := m.entry(userID)
+		if t.After(e.lastActiveAt) {
+			e.lastActiveAt = t
+		}
+		e.dirty = true
+	}
+}
@@ -0,0 +1,131 @@
+package presence
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock lets tests move time forward by hand.
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) now() time.Time { return f.t }
+
+func newTestStore(window time.Duration) (*Memory, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	m := NewMemory(window)
+	m.now = clock.now
+	return m, clock
+}
+
+func TestUnknownUser(t *testing.T) {
+	m, _ := newTestStore(time.Minute)
+	if p, ok := m.Get(1); ok || p.Online {
+		t.Fatalf("Get(1) = %+v, %v; want offline, false", p, ok)
+	}
+}
+
+func TestTouchIsOnlineForWindow(t *testing.T) {
+	m, clock := newTestStore(time.Minute)
+	m.Touch(1)
+	touchedAt := clock.t
+
+	if p, _ := m.Get(1); !p.Online || !p.LastActiveAt.Equal(touchedAt) {
+		t.Fatalf("Get(1) = %+v; want online, last active %v", p, touchedAt)
+	}
+
+	clock.t = clock.t.Add(time.Minute)
+	if p, ok := m.Get(1); !ok || p.Online || !p.LastActiveAt.Equal(touchedAt) {
+		t.Fatalf("Get(1) = %+v, %v; want offline, last active %v", p, ok, touchedAt)
+	}
+}
+
+func TestOnlineWhileAnyConnectionIsOpen(t *testing.T) {
+	m, clock := newTestStore(time.Minute)
+	m.Connect(1)
+	m.Connect(1)
+	clock.t = clock.t.Add(time.Hour)
+
+	if p, _ := m.Get(1); !p.Online {
+		t.Fatal("user with open connections is offline")
+	}
+
+	m.Disconnect(1)
+	clock.t = clock.t.Add(time.Hour)
+	if p, _ := m.Get(1); !p.Online {
+		t.Fatal("user offline with one connection still open")
+	}
+
+	m.Disconnect(1)
+	disconnectedAt := clock.t
+	clock.t = clock.t.Add(time.Minute)
+	p, _ := m.Get(1)
+	if p.Online {
+		t.Fatal("user online after every connection closed")
+	}
+	if !p.LastActiveAt.Equal(disconnectedAt) {
+		t.Fatalf("LastActiveAt = %v; want %v", p.LastActiveAt, disconnectedAt)
+	}
+
+	// Extra disconnects don't drive the count negative
+	m.Disconnect(1)
+	m.Connect(1)
+	clock.t = clock.t.Add(time.Hour)
+	if p, _ := m.Get(1); !p.Online {
+		t.Fatal("user offline after reconnecting")
+	}
+}
+
+func TestDrainCoalescesActivity(t *testing.T) {
+	m, clock := newTestStore(time.Minute)
+	m.Touch(1)
+	clock.t = clock.t.Add(time.Second)
+	m.Touch(1)
+	m.Touch(2)
+
+	drained := m.Drain()
+	if len(drained) != 2 || !drained[1].Equal(clock.t) || !drained[2].Equal(clock.t) {
+		t.Fatalf("Drain() = %v; want the latest time for users 1 and 2", drained)
+	}
+
+	if drained := m.Drain(); len(drained) != 0 {
+		t.Fatalf("second Drain() = %v; want nothing new", drained)
+	}
+
+	m.Touch(2)
+	if drained := m.Drain(); len(drained) != 1 || !drained[2].Equal(clock.t) {
+		t.Fatalf("Drain() = %v; want only user 2", drained)
+	}
+}
+
+func TestRestoreRedrainsWithoutGoingBack(t *testing.T) {
+	m, clock := newTestStore(time.Minute)
+	m.Touch(1)
+	m.Touch(2)
+	failed := m.Drain()
+
+	clock.t = clock.t.Add(time.Second)
+	m.Touch(2)
+	m.Restore(failed)
+
+	drained := m.Drain()
+	if len(drained) != 2 || !drained[1].Equal(failed[1]) || !drained[2].Equal(clock.t) {
+		t.Fatalf("Drain() = %v; want user 1's restored time and user 2's newer one", drained)
+	}
+}
+
+func TestDrainForgetsIdleUsers(t *testing.T) {
+	m, clock := newTestStore(time.Minute)
+	m.Touch(1)
+	m.Connect(2)
+	m.Drain()
+
+	clock.t = clock.t.Add(time.Minute)
+	m.Drain()
+
+	if _, ok := m.Get(1); ok {
+		t.Fatal("idle user still in the store")
+	}
+	if _, ok := m.Get(2); !ok {
+		t.Fatal("connected user was forgotten")
+	}
+}
@@ -0,0 +1,61 @@
+// Package privacy withholds the details users can choose not to share with
+// others, such as whether they are online or how far away they are.
+package privacy
+
+import (
+	"time"
+
+	"github.com/connectplus/models"
+)
+
+// Visibility is which optional details a user shares with other users.
+type Visibility struct {
+	OnlineStatus bool
+	LastActive   bool
+	Distance     bool
+}
+
+// Everything shares every detail. It applies to users who have never saved
+// their preferences.
+var Everything = Visibility{OnlineStatus: true, LastActive: true, Distance: true}
+
+// VisibilityOf returns the visibility chosen in the user's preferences, or
+// Everything if they have none.
+func VisibilityOf(pref *models.Preference) Visibility {
+	if pref == nil {
+		return Everything
+	}
+	return Visibility{
+		OnlineStatus: pref.ShowOnlineStatus,
+		LastActive:   pref.ShowLastActive,
+		Distance:     pref.ShowDistance,
+	}
+}
+
+// Details are the optional facts about a user shown alongside their profile.
+// Nil fields are left out of JSON responses.
+type Details struct {
+	// Whether the user is online right now
+	IsOnline *bool `json:"is_online,omitempty"`
+
+	// When the user was last active
+	LastActiveAt *time.Time `json:"last_active_at,omitempty"`
+
+	// Distance from the viewer in whole kilometers, when both have a position
+	// example: 12
+	DistanceKm *int `json:"distance_km,omitempty"`
+}
+
+// Apply returns d without the details v doesn't share.
+func (v Visibility) Apply(d Details) Details {
+	if !v.OnlineStatus {
+		d.IsOnline = nil
+	}
+	if !v.LastActive {
+		d.LastActiveAt = nil
+	}
+	if !v.Distance {
+		d.DistanceKm = nil
+	}
+	return d
+}
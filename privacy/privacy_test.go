@@ -0,0 +1,71 @@
+package privacy
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/connectplus/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func allDetails() Details {
+	online := true
+	lastActive := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	km := 12
+	return Details{IsOnline: &online, LastActiveAt: &lastActive, DistanceKm: &km}
+}
+
+func jsonKeys(t *testing.T, d Details) map[string]any {
+	b, err := json.Marshal(d)
+	require.NoError(t, err)
+	var keys map[string]any
+	require.NoError(t, json.Unmarshal(b, &keys))
+	return keys
+}
+
+func TestVisibilityOfDefaultsToEverything(t *testing.T) {
+	assert.Equal(t, Everything, VisibilityOf(nil))
+	assert.Equal(t, Visibility{LastActive: true}, VisibilityOf(&models.Preference{ShowLastActive: true}))
+}
+
+func TestApplySharesEverything(t *testing.T) {
+	keys := jsonKeys(t, Everything.Apply(allDetails()))
+	assert.Equal(t, true, keys["is_online"])
+	assert.Equal(t, "2026-10-18T12:00:00Z", keys["last_active_at"])
+	assert.Equal(t, float64(12), keys["distance_km"])
+}
+
+func TestApplyWithholdsHiddenDetails(t *testing.T) {
+	tests := []struct {
+		name     string
+		pref     models.Preference
+		withheld []string
+		shown    []string
+	}{
+		{"online status", models.Preference{ShowLastActive: true, ShowDistance: true}, []string{"is_online"}, []string{"last_active_at", "distance_km"}},
+		{"last active", models.Preference{ShowOnlineStatus: true, ShowDistance: true}, []string{"last_active_at"}, []string{"is_online", "distance_km"}},
+		{"distance", models.Preference{ShowOnlineStatus: true, ShowLastActive: true}, []string{"distance_km"}, []string{"is_online", "last_active_at"}},
+		{"everything", models.Preference{}, []string{"is_online", "last_active_at", "distance_km"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := jsonKeys(t, VisibilityOf(&tt.pref).Apply(allDetails()))
+			for _, key := range tt.withheld {
+				assert.NotContains(t, keys, key)
+			}
+			for _, key := range tt.shown {
+				assert.Contains(t, keys, key)
+			}
+		})
+	}
+}
+
+func TestApplyWithholdsOfflineStatus(t *testing.T) {
+	// Hiding online status must hide being offline too, or the absence of
+	// "is_online: true" would give it away
+	offline := false
+	d := Visibility{}.Apply(Details{IsOnline: &offline})
+	assert.Nil(t, d.IsOnline)
+}
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/connectplus/geo"
+	"github.com/connectplus/models"
+	"github.com/connectplus/presence"
+	"github.com/connectplus/privacy"
+	"gorm.io/gorm"
+)
+
+const (
+	birthDateLayout = "2006-01-02"
+	minProfileAge   = 18
+)
+
+// ProfileRequest represents the request payload for creating or updating a
+// profile. On update, omitted fields are left unchanged. Photos are managed
+// through the /profile/photos endpoints.
+// @swagger:model
+type ProfileRequest struct {
+	// Name shown to other users
+	// maxLength: 100
+	// example: John
+	DisplayName *string `json:"display_name"`
+
+	// maxLength: 500
+	// example: Love hiking and photography
+	Bio *string `json:"bio"`
+
+	// maxLength: 50
+	// example: Male
+	Gender *string `json:"gender"`
+
+	// Date of birth (YYYY-MM-DD)
+	// example: 1990-01-01
+	BirthDate *string `json:"birth_date"`
+
+	// maxLength: 100
+	// example: San Francisco, CA
+	Location *string `json:"location"`
+}
+
+// ProfileResponse represents a user's public profile
+// @swagger:model
+type ProfileResponse struct {
+	// ID of the user who owns the profile
+	// example: 42
+	UserID uint `json:"user_id"`
+
+	// Name shown to other users
+	// example: John
+	DisplayName string `json:"display_name"`
+
+	// example: Love hiking and photography
+	Bio string `json:"bio"`
+
+	// example: Male
+	Gender string `json:"gender"`
+
+	// Age in years, computed from the birth date
+	// example: 34
+	Age int `json:"age"`
+
+	// example: San Francisco, CA
+	Location string `json:"location"`
+
+	// Photo URLs in display order
+	Photos []string `json:"photos"`
+
+	// Birth date (YYYY-MM-DD), only included on the caller's own profile
+	// example: 1990-01-01
+	BirthDate string `json:"birth_date,omitempty"`
+
+	// Distance, and for matches online status and last-active time, on
+	// other users' profiles unless their owner has chosen to hide them
+	privacy.Details
+}
+
+func newProfileResponse(profile *models.Profile) ProfileResponse {
+	photos := profile.Photos
+	if photos == nil {
+		photos = []string{}
+	}
+	return ProfileResponse{
+		UserID:      profile.UserID,
+		DisplayName: profile.DisplayName,
+		Bio:         profile.Bio,
+		Gender:      profile.Gender,
+		Age:         profile.Age(time.Now()),
+		Location:    profile.Location,
+		Photos:      photos,
+	}
+}
+
+// newOwnProfileResponse includes the fields only the owner may see
+func newOwnProfileResponse(profile *models.Profile) ProfileResponse {
+	resp := newProfileResponse(profile)
+	if !profile.BirthDate.IsZero() {
+		resp.BirthDate = profile.BirthDate.Format(birthDateLayout)
+	}
+	return resp
+}
+
+// newViewedProfileResponses renders other users' profiles as the viewer sees
+// them. Every profile shown to another user must go through here, so that
+// the details its owner has hidden are left out.
+func newViewedProfileResponses(viewerID uint, profiles []models.Profile) ([]ProfileResponse, error) {
+	details, err := viewedDetails(viewerID, profiles)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]ProfileResponse, 0, len(profiles))
+	for i := range profiles {
+		p := newProfileResponse(&profiles[i])
+		p.Details = details[profiles[i].UserID]
+		resp = append(resp, p)
+	}
+	return resp, nil
+}
+
+// viewedDetails returns the optional details each profile's owner shares
+// with the viewer: their distance with anyone, and whether they are online
+// and when they were last active with their matches only, less whatever
+// each owner has chosen to hide
+func viewedDetails(viewerID uint, profiles []models.Profile) (map[uint]privacy.Details, error) {
+	details := make(map[uint]privacy.Details, len(profiles))
+	if len(profiles) == 0 {
+		return details, nil
+	}
+
+	viewer, err := profileRepo.FindByUserID(viewerID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		viewer = nil
+	}
+
+	ownerIDs := make([]uint, len(profiles))
+	for i, profile := range profiles {
+		ownerIDs[i] = profile.UserID
+	}
+	preferences, err := preferenceRepo.FindByUserIDs(ownerIDs)
+	if err != nil {
+		return nil, err
+	}
+	visibility := make(map[uint]privacy.Visibility, len(preferences))
+	for i := range preferences {
+		visibility[preferences[i].UserID] = privacy.VisibilityOf(&preferences[i])
+	}
+
+	matches, err := matchRepo.FindByUserIDAndStatus(viewerID, models.MatchAccepted)
+	if err != nil {
+		return nil, err
+	}
+	matched := make(map[uint]bool, len(matches))
+	for i := range matches {
+		matched[matches[i].OtherUserID(viewerID)] = true
+	}
+	var matchedIDs []uint
+	for _, id := range ownerIDs {
+		if matched[id] {
+			matchedIDs = append(matchedIDs, id)
+		}
+	}
+	owners, err := userRepo.FindByIDs(matchedIDs)
+	if err != nil {
+		return nil, err
+	}
+	presences := make(map[uint]presence.Presence, len(owners))
+	for i := range owners {
+		presences[owners[i].ID] = userPresence(&owners[i])
+	}
+
+	for i := range profiles {
+		profile := &profiles[i]
+		d := privacy.Details{DistanceKm: distanceKm(viewer, profile)}
+		if p, ok := presences[profile.UserID]; ok {
+			d.IsOnline = &p.Online
+			if !p.LastActiveAt.IsZero() {
+				d.LastActiveAt = &p.LastActiveAt
+			}
+		}
+
+		v, ok := visibility[profile.UserID]
+		if !ok {
+			v = privacy.Everything
+		}
+		details[profile.UserID] = v.Apply(d)
+	}
+	return details, nil
+}
+
+// distanceKm returns how far apart two profiles are, or nil unless both
+// have a position
+func distanceKm(viewer, profile *models.Profile) *int {
+	if viewer == nil || !viewer.HasCoordinates() || !profile.HasCoordinates() {
+		return nil
+	}
+	// Round to whole kilometers, never below 1, so exact positions can't be
+	// triangulated from repeated requests
+	km := int(math.Max(1, math.Round(geo.Distance(*viewer.Latitude, *viewer.Longitude, *profile.Latitude, *profile.Longitude))))
+	return &km
+}
+
+// apply validates the supplied fields and copies them onto profile,
+// returning the columns that changed or a message describing the first
+// invalid field
+func (req *ProfileRequest) apply(profile *models.Profile) (columns []string, errMsg string) {
+	if req.DisplayName != nil {
+		name := strings.TrimSpace(*req.DisplayName)
+		if name == "" || len(name) > 100 {
+			return nil, "Display name must be between 1 and 100 characters"
+		}
+		profile.DisplayName = name
+		columns = append(columns, "display_name")
+	}
+
+	if req.Bio != nil {
+		if len(*req.Bio) > 500 {
+			return nil, "Bio must be at most 500 characters"
+		}
+		profile.Bio = *req.Bio
+		columns = append(columns, "bio")
+	}
+
+	if req.Gender != nil {
+		if len(*req.Gender) > 50 {
+			return nil, "Gender must be at most 50 characters"
+		}
+		profile.Gender = *req.Gender
+		columns = append(columns, "gender")
+	}
+
+	if req.BirthDate != nil {
+		birthDate, err := time.Parse(birthDateLayout, *req.BirthDate)
+		if err != nil {
+			return nil, "Invalid date format. Use YYYY-MM-DD"
+		}
+		candidate := models.Profile{BirthDate: birthDate}
+		if candidate.Age(time.Now()) < minProfileAge {
+			return nil, "You must be at least 18 years old"
+		}
+		profile.BirthDate = birthDate
+		columns = append(columns, "birth_date")
+	}
+
+	if req.Location != nil {
+		if len(*req.Location) > 100 {
+			return nil, "Location must be at most 100 characters"
+		}
+		profile.Location = *req.Location
+		columns = append(columns, "location")
+	}
+
+	return columns, ""
+}
+
+// profileHandler routes requests for the caller's own profile
+func profileHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		getProfileHandler(w, r)
+	case http.MethodPost:
+		createProfileHandler(w, r)
+	case http.MethodPatch:
+		patchProfileHandler(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// getProfileHandler godoc
+// @Summary Get own profile
+// @Description Get the authenticated user's profile
+// @Tags profiles
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} ProfileResponse
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /profile [get]
+func getProfileHandler(w http.ResponseWriter, r *http.Request) {
+	profile, err := profileRepo.FindByUserID(currentUserID(r))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Profile not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newOwnProfileResponse(profile))
+}
+
+// createProfileHandler godoc
+// @Summary Create profile
+// @Description Create the authenticated user's profile. Display name and birth date are required.
+// @Tags profiles
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param profile body ProfileRequest true "Profile details"
+// @Success 201 {object} ProfileResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /profile [post]
+func createProfileHandler(w http.ResponseWriter, r *http.Request) {
+	var req ProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.DisplayName == nil || req.BirthDate == nil {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	userID := currentUserID(r)
+	profile := &models.Profile{UserID: userID}
+	if _, errMsg := req.apply(profile); errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	// Check if the user already has a profile
+	if _, err := profileRepo.FindByUserID(userID); err == nil {
+		http.Error(w, "Profile already exists", http.StatusConflict)
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := profileRepo.Create(profile); err != nil {
+		http.Error(w, "Failed to create profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newOwnProfileResponse(profile))
+}
+
+// patchProfileHandler godoc
+// @Summary Update profile
+// @Description Update the authenticated user's profile. Only the fields present in the request are changed.
+// @Tags profiles
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param profile body ProfileRequest true "Fields to change"
+// @Success 200 {object} ProfileResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /profile [patch]
+func patchProfileHandler(w http.ResponseWriter, r *http.Request) {
+	var req ProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := profileRepo.FindByUserID(currentUserID(r))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Profile not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	columns, errMsg := req.apply(profile)
+	if errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	if len(columns) > 0 {
+		if err := profileRepo.UpdateColumns(profile, append(columns, "updated_at")...); err != nil {
+			http.Error(w, "Failed to update profile", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newOwnProfileResponse(profile))
+}
+
+// getUserProfileHandler godoc
+// @Summary Get a user's profile
+// @Description Get another user's public profile. Online status and last-active time are only shown to the user's matches. Each of them, and distance, is left out if the user has chosen to hide it. Users who blocked or were blocked by the caller, and suspended or deactivated users, aren't found.
+// @Tags profiles
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param userID path int true "User ID"
+// @Success 200 {object} ProfileResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /profiles/{userID} [get]
+func getUserProfileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := strconv.ParseUint(r.PathValue("userID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	// Blocked users are treated as gone in both directions
+	blocked, err := blockRepo.IsBlocked(currentUserID(r), uint(userID))
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if blocked {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	// Suspended and deactivated users are hidden like missing ones
+	profile, err := profileRepo.FindActiveByUserID(uint(userID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Profile not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := newViewedProfileResponses(currentUserID(r), []models.Profile{*profile})
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp[0])
+}
@@ -0,0 +1,46 @@
+package repositories
+
+import (
+    "github.com/connectplus/models"
+    "gorm.io/gorm"
+)
+
+type AuditLogRepository interface {
+    Create(entry *models.AuditLog) error
+    // List returns entries newest first.
+    List(limit, offset int) ([]models.AuditLog, error)
+    // FindByTarget returns the entries about one target, newest first.
+    FindByTarget(targetType string, targetID uint) ([]models.AuditLog, error)
+}
+
+type auditLogRepository struct {
+    db *gorm.DB
+}
+
+func NewAuditLogRepository(db *gorm.DB) AuditLogRepository {
+    return &auditLogRepository{db: db}
+}
+
+func (r *auditLogRepository) Create(entry *models.AuditLog) error {
+    return r.db.Create(entry).Error
+}
+
+func (r *auditLogRepository) List(limit, offset int) ([]models.AuditLog, error) {
+    var entries []models.AuditLog
+    query := r.db.Order("created_at desc, id desc")
+    if limit > 0 {
+        query = query.Limit(limit)
+    }
+    if offset > 0 {
+        query = query.Offset(offset)
+    }
+    err := query.Find(&entries).Error
+    return entries, err
+}
+
+func (r *auditLogRepository) FindByTarget(targetType string, targetID uint) ([]models.AuditLog, error) {
+    var entries []models.AuditLog
+    err := r.db.Where("target_type = ? AND target_id = ?", targetType, targetID).
+        Order("created_at desc, id desc").Find(&entries).Error
+    return entries, err
+}
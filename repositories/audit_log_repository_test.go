@@ -0,0 +1,72 @@
+package repositories
+
+import (
+    "testing"
+
+    "github.com/connectplus/models"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/suite"
+    "gorm.io/driver/sqlite"
+    "gorm.io/gorm"
+)
+
+type AuditLogRepositoryTestSuite struct {
+    suite.Suite
+    db   *gorm.DB
+    repo AuditLogRepository
+}
+
+func (suite *AuditLogRepositoryTestSuite) SetupTest() {
+    var err error
+    suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+    assert.NoError(suite.T(), err)
+
+    err = suite.db.AutoMigrate(&models.User{}, &models.AuditLog{})
+    assert.NoError(suite.T(), err)
+
+    suite.repo = NewAuditLogRepository(suite.db)
+}
+
+func (suite *AuditLogRepositoryTestSuite) TearDownTest() {
+    db, _ := suite.db.DB()
+    db.Close()
+}
+
+func (suite *AuditLogRepositoryTestSuite) TestCreateEntry() {
+    entry := &models.AuditLog{ActorID: 1, Action: "suspend_user", TargetType: "user", TargetID: 2, Details: "Spam"}
+    err := suite.repo.Create(entry)
+    assert.NoError(suite.T(), err)
+    assert.NotZero(suite.T(), entry.ID)
+    assert.False(suite.T(), entry.CreatedAt.IsZero())
+}
+
+func (suite *AuditLogRepositoryTestSuite) TestListNewestFirst() {
+    for targetID := uint(1); targetID <= 3; targetID++ {
+        suite.repo.Create(&models.AuditLog{ActorID: 1, Action: "view_user", TargetType: "user", TargetID: targetID})
+    }
+
+    entries, err := suite.repo.List(0, 0)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), entries, 3)
+    assert.Equal(suite.T(), uint(3), entries[0].TargetID)
+
+    page, err := suite.repo.List(2, 2)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), page, 1)
+    assert.Equal(suite.T(), uint(1), page[0].TargetID)
+}
+
+func (suite *AuditLogRepositoryTestSuite) TestFindByTarget() {
+    suite.repo.Create(&models.AuditLog{ActorID: 1, Action: "view_user", TargetType: "user", TargetID: 2})
+    suite.repo.Create(&models.AuditLog{ActorID: 1, Action: "suspend_user", TargetType: "user", TargetID: 2})
+    suite.repo.Create(&models.AuditLog{ActorID: 1, Action: "review_report", TargetType: "report", TargetID: 2})
+
+    entries, err := suite.repo.FindByTarget("user", 2)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), entries, 2)
+    assert.Equal(suite.T(), "suspend_user", entries[0].Action)
+}
+
+func TestAuditLogRepositorySuite(t *testing.T) {
+    suite.Run(t, new(AuditLogRepositoryTestSuite))
+}
@@ -0,0 +1,87 @@
+package repositories
+
+import (
+    "errors"
+    "time"
+
+    "github.com/connectplus/models"
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+)
+
+type BlockRepository interface {
+    // Create records that blocker blocked blocked. In the same transaction
+    // any pending or accepted match between them moves to MatchBlocked and
+    // their conversation is hidden from both. Blocking someone again
+    // returns the existing block.
+    Create(blockerID, blockedID uint) (*models.Block, error)
+    // IsBlocked reports whether either user has blocked the other.
+    IsBlocked(user1ID, user2ID uint) (bool, error)
+    FindByBlockerID(blockerID uint) ([]models.Block, error)
+}
+
+type blockRepository struct {
+    db *gorm.DB
+}
+
+func NewBlockRepository(db *gorm.DB) BlockRepository {
+    return &blockRepository{db: db}
+}
+
+func (r *blockRepository) Create(blockerID, blockedID uint) (*models.Block, error) {
+    var block models.Block
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+            Create(&models.Block{BlockerID: blockerID, BlockedID: blockedID}).Error; err != nil {
+            return err
+        }
+        if err := tx.Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).First(&block).Error; err != nil {
+            return err
+        }
+
+        var match models.Match
+        err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+            Where("(user1_id = ? AND user2_id = ?) OR (user1_id = ? AND user2_id = ?)",
+                blockerID, blockedID, blockedID, blockerID).
+            First(&match).Error
+        if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+            return err
+        }
+        if err == nil && match.Status.CanTransitionTo(models.MatchBlocked) {
+            from := match.Status
+            if err := tx.Model(&match).Update("status", models.MatchBlocked).Error; err != nil {
+                return err
+            }
+            if err := tx.Create(&models.MatchEvent{
+                MatchID:    match.ID,
+                ActorID:    &blockerID,
+                FromStatus: from,
+                ToStatus:   models.MatchBlocked,
+            }).Error; err != nil {
+                return err
+            }
+        }
+
+        // Hide messages left over from an earlier match too
+        return hideConversation(tx, blockerID, blockedID, time.Now())
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &block, nil
+}
+
+func (r *blockRepository) IsBlocked(user1ID, user2ID uint) (bool, error) {
+    var count int64
+    err := r.db.Model(&models.Block{}).
+        Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)",
+            user1ID, user2ID, user2ID, user1ID).
+        Count(&count).Error
+    return count > 0, err
+}
+
+func (r *blockRepository) FindByBlockerID(blockerID uint) ([]models.Block, error) {
+    var blocks []models.Block
+    err := r.db.Where("blocker_id = ?", blockerID).Order("created_at desc, id desc").Find(&blocks).Error
+    return blocks, err
+}
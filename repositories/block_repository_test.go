@@ -0,0 +1,122 @@
+package repositories
+
+import (
+    "testing"
+
+    "github.com/connectplus/models"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/suite"
+    "gorm.io/driver/sqlite"
+    "gorm.io/gorm"
+)
+
+type BlockRepositoryTestSuite struct {
+    suite.Suite
+    db   *gorm.DB
+    repo BlockRepository
+}
+
+func (suite *BlockRepositoryTestSuite) SetupTest() {
+    var err error
+    suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+    assert.NoError(suite.T(), err)
+
+    err = suite.db.AutoMigrate(&models.User{}, &models.Block{}, &models.Match{}, &models.MatchEvent{}, &models.Message{})
+    assert.NoError(suite.T(), err)
+
+    suite.repo = NewBlockRepository(suite.db)
+}
+
+func (suite *BlockRepositoryTestSuite) TearDownTest() {
+    db, _ := suite.db.DB()
+    db.Close()
+}
+
+func (suite *BlockRepositoryTestSuite) TestCreateBlock() {
+    block, err := suite.repo.Create(1, 2)
+    assert.NoError(suite.T(), err)
+    assert.NotZero(suite.T(), block.ID)
+    assert.Equal(suite.T(), uint(1), block.BlockerID)
+    assert.Equal(suite.T(), uint(2), block.BlockedID)
+
+    blocks, err := suite.repo.FindByBlockerID(1)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), blocks, 1)
+}
+
+func (suite *BlockRepositoryTestSuite) TestCreateBlockTwiceReturnsExisting() {
+    first, err := suite.repo.Create(1, 2)
+    assert.NoError(suite.T(), err)
+
+    second, err := suite.repo.Create(1, 2)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), first.ID, second.ID)
+
+    var count int64
+    suite.db.Model(&models.Block{}).Count(&count)
+    assert.Equal(suite.T(), int64(1), count)
+}
+
+func (suite *BlockRepositoryTestSuite) TestIsBlockedEitherDirection() {
+    _, err := suite.repo.Create(1, 2)
+    assert.NoError(suite.T(), err)
+
+    blocked, err := suite.repo.IsBlocked(1, 2)
+    assert.NoError(suite.T(), err)
+    assert.True(suite.T(), blocked)
+
+    blocked, err = suite.repo.IsBlocked(2, 1)
+    assert.NoError(suite.T(), err)
+    assert.True(suite.T(), blocked)
+
+    blocked, err = suite.repo.IsBlocked(1, 3)
+    assert.NoError(suite.T(), err)
+    assert.False(suite.T(), blocked)
+}
+
+func (suite *BlockRepositoryTestSuite) TestBlockEndsMatchAndHidesConversation() {
+    accepted := &models.Match{User1ID: 1, User2ID: 2, Status: models.MatchAccepted}
+    pending := &models.Match{User1ID: 3, User2ID: 1, Status: models.MatchPending}
+    suite.db.Create(accepted)
+    suite.db.Create(pending)
+    suite.db.Create(&models.Message{SenderID: 2, ReceiverID: 1, Content: "Hi"})
+    suite.db.Create(&models.Message{SenderID: 1, ReceiverID: 3, Content: "Hello"})
+
+    _, err := suite.repo.Create(1, 2)
+    assert.NoError(suite.T(), err)
+    // Blocked by the other participant of a pending match
+    _, err = suite.repo.Create(3, 1)
+    assert.NoError(suite.T(), err)
+
+    for _, match := range []*models.Match{accepted, pending} {
+        var stored models.Match
+        suite.db.First(&stored, match.ID)
+        assert.Equal(suite.T(), models.MatchBlocked, stored.Status)
+
+        var events []models.MatchEvent
+        suite.db.Where("match_id = ?", match.ID).Find(&events)
+        assert.Len(suite.T(), events, 1)
+        assert.Equal(suite.T(), match.Status, events[0].FromStatus)
+        assert.Equal(suite.T(), models.MatchBlocked, events[0].ToStatus)
+    }
+
+    var visible int64
+    suite.db.Model(&models.Message{}).Where("hidden_at IS NULL").Count(&visible)
+    assert.Equal(suite.T(), int64(0), visible)
+}
+
+func (suite *BlockRepositoryTestSuite) TestBlockLeavesFinalMatchUnchanged() {
+    declined := &models.Match{User1ID: 1, User2ID: 2, Status: models.MatchDeclined}
+    suite.db.Create(declined)
+
+    _, err := suite.repo.Create(2, 1)
+    assert.NoError(suite.T(), err)
+
+    var stored models.Match
+    suite.db.First(&stored, declined.ID)
+    assert.Equal(suite.T(), models.MatchDeclined, stored.Status)
+}
+
+func TestBlockRepositorySuite(t *testing.T) {
+    suite.Run(t, new(BlockRepositoryTestSuite))
+}
@@ -0,0 +1,123 @@
+package repositories
+
+import (
+    "errors"
+    "time"
+
+    "github.com/connectplus/models"
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+)
+
+// ErrExportPending is returned when requesting an export for a user who
+// already has one being prepared.
+var ErrExportPending = errors.New("data export already pending")
+
+type DataExportRepository interface {
+    // Create records a new pending export. It returns ErrExportPending if
+    // the user already has one.
+    Create(export *models.DataExport) error
+    FindByID(exportID uint) (*models.DataExport, error)
+    // FindByUserID returns the user's exports newest first.
+    FindByUserID(userID uint) ([]models.DataExport, error)
+    // FindByStatus returns every export in the given status, oldest first.
+    FindByStatus(status models.DataExportStatus) ([]models.DataExport, error)
+    // MarkReady records a finished archive. It returns
+    // gorm.ErrRecordNotFound if the export is no longer pending.
+    MarkReady(exportID uint, blobKey string, size int64, expiresAt time.Time) error
+    MarkFailed(exportID uint) error
+    // FindExpired returns ready exports whose archive expired before now.
+    FindExpired(now time.Time) ([]models.DataExport, error)
+    MarkExpired(exportID uint) error
+}
+
+type dataExportRepository struct {
+    db *gorm.DB
+}
+
+func NewDataExportRepository(db *gorm.DB) DataExportRepository {
+    return &dataExportRepository{db: db}
+}
+
+func (r *dataExportRepository) Create(export *models.DataExport) error {
+    export.Status = models.DataExportPending
+    result := r.db.Clauses(clause.OnConflict{
+        Columns:     []clause.Column{{Name: "user_id"}},
+        TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "status = 'pending'"}}},
+        DoNothing:   true,
+    }).Create(export)
+    if result.Error != nil {
+        return result.Error
+    }
+    if result.RowsAffected == 0 {
+        return ErrExportPending
+    }
+    return nil
+}
+
+func (r *dataExportRepository) FindByID(exportID uint) (*models.DataExport, error) {
+    var export models.DataExport
+    err := r.db.First(&export, exportID).Error
+    if err != nil {
+        return nil, err
+    }
+    return &export, nil
+}
+
+func (r *dataExportRepository) FindByUserID(userID uint) ([]models.DataExport, error) {
+    var exports []models.DataExport
+    err := r.db.Where("user_id = ?", userID).Order("created_at desc, id desc").Find(&exports).Error
+    return exports, err
+}
+
+func (r *dataExportRepository) FindByStatus(status models.DataExportStatus) ([]models.DataExport, error) {
+    var exports []models.DataExport
+    err := r.db.Where("status = ?", status).Order("created_at asc, id asc").Find(&exports).Error
+    return exports, err
+}
+
+func (r *dataExportRepository) MarkReady(exportID uint, blobKey string, size int64, expiresAt time.Time) error {
+    now := time.Now()
+    result := r.db.Model(&models.DataExport{}).
+        Where("id = ? AND status = ?", exportID, models.DataExportPending).
+        Updates(map[string]interface{}{
+            "status":       models.DataExportReady,
+            "blob_key":     blobKey,
+            "size_bytes":   size,
+            "expires_at":   expiresAt,
+            "completed_at": now,
+        })
+    if result.Error != nil {
+        return result.Error
+    }
+    if result.RowsAffected == 0 {
+        return gorm.ErrRecordNotFound
+    }
+    return nil
+}
+
+func (r *dataExportRepository) MarkFailed(exportID uint) error {
+    return r.db.Model(&models.DataExport{}).
+        Where("id = ? AND status = ?", exportID, models.DataExportPending).
+        Updates(map[string]interface{}{
+            "status":       models.DataExportFailed,
+            "completed_at": time.Now(),
+        }).Error
+}
+
+func (r *dataExportRepository) FindExpired(now time.Time) ([]models.DataExport, error) {
+    var exports []models.DataExport
+    err := r.db.Where("status = ? AND expires_at <= ?", models.DataExportReady, now).
+        Order("expires_at asc").
+        Find(&exports).Error
+    return exports, err
+}
+
+func (r *dataExportRepository) MarkExpired(exportID uint) error {
+    return r.db.Model(&models.DataExport{}).
+        Where("id = ?", exportID).
+        Updates(map[string]interface{}{
+            "status":   models.DataExportExpired,
+            "blob_key": "",
+        }).Error
+}
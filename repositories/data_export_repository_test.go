@@ -0,0 +1,143 @@
+package repositories
+
+import (
+    "testing"
+    "time"
+
+    "github.com/connectplus/models"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/suite"
+    "gorm.io/driver/sqlite"
+    "gorm.io/gorm"
+)
+
+type DataExportRepositoryTestSuite struct {
+    suite.Suite
+    db   *gorm.DB
+    repo DataExportRepository
+}
+
+func (suite *DataExportRepositoryTestSuite) SetupTest() {
+    var err error
+    suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+    assert.NoError(suite.T(), err)
+
+    err = suite.db.AutoMigrate(&models.User{}, &models.DataExport{})
+    assert.NoError(suite.T(), err)
+
+    suite.repo = NewDataExportRepository(suite.db)
+}
+
+func (suite *DataExportRepositoryTestSuite) TearDownTest() {
+    db, _ := suite.db.DB()
+    db.Close()
+}
+
+func (suite *DataExportRepositoryTestSuite) TestCreateExport() {
+    export := &models.DataExport{UserID: 1}
+    err := suite.repo.Create(export)
+    assert.NoError(suite.T(), err)
+    assert.NotZero(suite.T(), export.ID)
+
+    found, err := suite.repo.FindByID(export.ID)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), models.DataExportPending, found.Status)
+    assert.False(suite.T(), found.IsDownloadable(time.Now()))
+}
+
+func (suite *DataExportRepositoryTestSuite) TestCreateWhilePending() {
+    first := &models.DataExport{UserID: 1}
+    assert.NoError(suite.T(), suite.repo.Create(first))
+
+    err := suite.repo.Create(&models.DataExport{UserID: 1})
+    assert.ErrorIs(suite.T(), err, ErrExportPending)
+    assert.NoError(suite.T(), suite.repo.Create(&models.DataExport{UserID: 2}))
+
+    // Once the first is done another can be requested
+    suite.repo.MarkFailed(first.ID)
+    assert.NoError(suite.T(), suite.repo.Create(&models.DataExport{UserID: 1}))
+
+    exports, _ := suite.repo.FindByUserID(1)
+    assert.Len(suite.T(), exports, 2)
+}
+
+func (suite *DataExportRepositoryTestSuite) TestFindNonExistentExport() {
+    _, err := suite.repo.FindByID(999)
+    assert.ErrorIs(suite.T(), err, gorm.ErrRecordNotFound)
+}
+
+func (suite *DataExportRepositoryTestSuite) TestFindByUserIDNewestFirst() {
+    first := &models.DataExport{UserID: 1}
+    second := &models.DataExport{UserID: 1}
+    suite.repo.Create(first)
+    suite.repo.MarkFailed(first.ID)
+    suite.repo.Create(second)
+    suite.repo.Create(&models.DataExport{UserID: 2})
+
+    exports, err := suite.repo.FindByUserID(1)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), exports, 2)
+    assert.Equal(suite.T(), second.ID, exports[0].ID)
+}
+
+func (suite *DataExportRepositoryTestSuite) TestMarkReady() {
+    export := &models.DataExport{UserID: 1}
+    suite.repo.Create(export)
+    expiresAt := time.Now().Add(time.Hour)
+
+    err := suite.repo.MarkReady(export.ID, "exports/1/a.zip", 42, expiresAt)
+    assert.NoError(suite.T(), err)
+
+    found, _ := suite.repo.FindByID(export.ID)
+    assert.Equal(suite.T(), models.DataExportReady, found.Status)
+    assert.Equal(suite.T(), "exports/1/a.zip", found.BlobKey)
+    assert.Equal(suite.T(), int64(42), found.SizeBytes)
+    assert.NotNil(suite.T(), found.CompletedAt)
+    assert.True(suite.T(), found.IsDownloadable(time.Now()))
+    assert.False(suite.T(), found.IsDownloadable(expiresAt.Add(time.Second)))
+
+    // Only pending exports can be completed
+    err = suite.repo.MarkReady(export.ID, "exports/1/b.zip", 1, expiresAt)
+    assert.ErrorIs(suite.T(), err, gorm.ErrRecordNotFound)
+}
+
+func (suite *DataExportRepositoryTestSuite) TestMarkFailed() {
+    export := &models.DataExport{UserID: 1}
+    suite.repo.Create(export)
+
+    assert.NoError(suite.T(), suite.repo.MarkFailed(export.ID))
+
+    found, _ := suite.repo.FindByID(export.ID)
+    assert.Equal(suite.T(), models.DataExportFailed, found.Status)
+
+    pending, err := suite.repo.FindByStatus(models.DataExportPending)
+    assert.NoError(suite.T(), err)
+    assert.Empty(suite.T(), pending)
+}
+
+func (suite *DataExportRepositoryTestSuite) TestFindExpiredAndMarkExpired() {
+    now := time.Now()
+    expired := &models.DataExport{UserID: 1}
+    current := &models.DataExport{UserID: 1}
+    suite.repo.Create(expired)
+    suite.repo.Create(current)
+    suite.repo.MarkReady(expired.ID, "exports/1/old.zip", 1, now.Add(-time.Minute))
+    suite.repo.MarkReady(current.ID, "exports/1/new.zip", 1, now.Add(time.Hour))
+
+    exports, err := suite.repo.FindExpired(now)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), exports, 1)
+    assert.Equal(suite.T(), expired.ID, exports[0].ID)
+
+    assert.NoError(suite.T(), suite.repo.MarkExpired(expired.ID))
+    found, _ := suite.repo.FindByID(expired.ID)
+    assert.Equal(suite.T(), models.DataExportExpired, found.Status)
+    assert.Empty(suite.T(), found.BlobKey)
+
+    exports, _ = suite.repo.FindExpired(now)
+    assert.Empty(suite.T(), exports)
+}
+
+func TestDataExportRepositorySuite(t *testing.T) {
+    suite.Run(t, new(DataExportRepositoryTestSuite))
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+    "github.com/connectplus/models"
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+)
+
+type DeviceRepository interface {
+    // Register records that the device's user has its token, replacing any
+    // earlier registration of the same token, whoever made it. It fills in
+    // the device's ID and reports whether the token was new.
+    Register(device *models.Device) (bool, error)
+    FindByUserID(userID uint) ([]models.Device, error)
+    // Delete removes one of the user's devices. It returns
+    // gorm.ErrRecordNotFound if the user has no such device.
+    Delete(userID, deviceID uint) error
+    // DeleteByToken removes the device with the token, if any.
+    DeleteByToken(token string) error
+}
+
+type deviceRepository struct {
+    db *gorm.DB
+}
+
+func NewDeviceRepository(db *gorm.DB) DeviceRepository {
+    return &deviceRepository{db: db}
+}
+
+func (r *deviceRepository) Register(device *models.Device) (bool, error) {
+    var count int64
+    if err := r.db.Model(&models.Device{}).Where("token = ?", device.Token).Count(&count).Error; err != nil {
+        return false, err
+    }
+
+    // Upsert so concurrent registrations of a new token can't collide on the
+    // unique index. The token may have moved to another account or app
+    // version, for example when someone signs out and a different user
+    // signs in.
+    err := r.db.Clauses(clause.OnConflict{
+        Columns:   []clause.Column{{Name: "token"}},
+        DoUpdates: clause.AssignmentColumns([]string{"user_id", "platform", "app_version", "last_seen_at", "updated_at"}),
+    }).Create(device).Error
+    if err != nil {
+        return false, err
+    }
+
+    // Reload to pick up the ID and creation time of a replaced registration
+    if err := r.db.Where("token = ?", device.Token).First(device).Error; err != nil {
+        return false, err
+    }
+    return count == 0, nil
+}
+
+func (r *deviceRepository) FindByUserID(userID uint) ([]models.Device, error) {
+    var devices []models.Device
+    err := r.db.Where("user_id = ?", userID).Order("last_seen_at desc").Find(&devices).Error
+    return devices, err
+}
+
+func (r *deviceRepository) Delete(userID, deviceID uint) error {
+    result := r.db.Where("id = ? AND user_id = ?", deviceID, userID).Delete(&models.Device{})
+    if result.Error != nil {
+        return result.Error
+    }
+    if result.RowsAffected == 0 {
+        return gorm.ErrRecordNotFound
+    }
+    return nil
+}
+
+func (r *deviceRepository) DeleteByToken(token string) error {
+    return r.db.Where("token = ?", token).Delete(&models.Device{}).Error
+}
@@ -0,0 +1,144 @@
+package repositories
+
+import (
+    "testing"
+    "time"
+
+    "github.com/connectplus/models"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/suite"
+    "gorm.io/driver/sqlite"
+    "gorm.io/gorm"
+)
+
+type DeviceRepositoryTestSuite struct {
+    suite.Suite
+    db   *gorm.DB
+    repo DeviceRepository
+}
+
+func (suite *DeviceRepositoryTestSuite) SetupTest() {
+    var err error
+    suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+    assert.NoError(suite.T(), err)
+
+    err = suite.db.AutoMigrate(&models.User{}, &models.Device{})
+    assert.NoError(suite.T(), err)
+
+    suite.repo = NewDeviceRepository(suite.db)
+}
+
+func (suite *DeviceRepositoryTestSuite) TearDownTest() {
+    db, _ := suite.db.DB()
+    db.Close()
+}
+
+func (suite *DeviceRepositoryTestSuite) register(userID uint, platform models.DevicePlatform, token string) *models.Device {
+    device := &models.Device{
+        UserID:     userID,
+        Platform:   platform,
+        Token:      token,
+        AppVersion: "1.0.0",
+        LastSeenAt: time.Now(),
+    }
+    created, err := suite.repo.Register(device)
+    assert.NoError(suite.T(), err)
+    assert.True(suite.T(), created)
+    return device
+}
+
+func (suite *DeviceRepositoryTestSuite) TestRegisterDevice() {
+    device := suite.register(1, models.DeviceIOS, "apns-token")
+    assert.NotZero(suite.T(), device.ID)
+
+    devices, err := suite.repo.FindByUserID(1)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), devices, 1)
+    assert.Equal(suite.T(), models.DeviceIOS, devices[0].Platform)
+    assert.Equal(suite.T(), "apns-token", devices[0].Token)
+    assert.Equal(suite.T(), "1.0.0", devices[0].AppVersion)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestRegisterDuplicateTokenReplacesIt() {
+    first := suite.register(1, models.DeviceAndroid, "fcm-token")
+    suite.db.Model(first).Update("last_seen_at", time.Now().Add(-24*time.Hour))
+
+    // Another user signs in on the same phone after an app update
+    again := &models.Device{
+        UserID:     2,
+        Platform:   models.DeviceAndroid,
+        Token:      "fcm-token",
+        AppVersion: "1.1.0",
+        LastSeenAt: time.Now(),
+    }
+    created, err := suite.repo.Register(again)
+    assert.NoError(suite.T(), err)
+    assert.False(suite.T(), created)
+    assert.Equal(suite.T(), first.ID, again.ID)
+    assert.True(suite.T(), first.CreatedAt.Equal(again.CreatedAt))
+
+    var count int64
+    suite.db.Model(&models.Device{}).Count(&count)
+    assert.Equal(suite.T(), int64(1), count)
+
+    devices, _ := suite.repo.FindByUserID(1)
+    assert.Empty(suite.T(), devices)
+    devices, _ = suite.repo.FindByUserID(2)
+    assert.Len(suite.T(), devices, 1)
+    assert.Equal(suite.T(), "1.1.0", devices[0].AppVersion)
+    assert.WithinDuration(suite.T(), time.Now(), devices[0].LastSeenAt, time.Minute)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestFindByUserID() {
+    suite.register(1, models.DeviceIOS, "phone")
+    suite.register(1, models.DeviceWeb, "browser")
+    suite.register(2, models.DeviceAndroid, "other")
+
+    devices, err := suite.repo.FindByUserID(1)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), devices, 2)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestDeleteDevice() {
+    device := suite.register(1, models.DeviceIOS, "apns-token")
+
+    err := suite.repo.Delete(1, device.ID)
+    assert.NoError(suite.T(), err)
+
+    devices, _ := suite.repo.FindByUserID(1)
+    assert.Empty(suite.T(), devices)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestDeleteOtherUsersDevice() {
+    device := suite.register(1, models.DeviceIOS, "apns-token")
+
+    err := suite.repo.Delete(2, device.ID)
+    assert.ErrorIs(suite.T(), err, gorm.ErrRecordNotFound)
+
+    devices, _ := suite.repo.FindByUserID(1)
+    assert.Len(suite.T(), devices, 1)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestDeleteNonExistentDevice() {
+    err := suite.repo.Delete(1, 999)
+    assert.ErrorIs(suite.T(), err, gorm.ErrRecordNotFound)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestDeleteByToken() {
+    suite.register(1, models.DeviceAndroid, "stale")
+    suite.register(1, models.DeviceWeb, "fresh")
+
+    err := suite.repo.DeleteByToken("stale")
+    assert.NoError(suite.T(), err)
+    // Unknown tokens are not an error
+    err = suite.repo.DeleteByToken("unknown")
+    assert.NoError(suite.T(), err)
+
+    devices, _ := suite.repo.FindByUserID(1)
+    assert.Len(suite.T(), devices, 1)
+    assert.Equal(suite.T(), "fresh", devices[0].Token)
+}
+
+func TestDeviceRepositorySuite(t *testing.T) {
+    suite.Run(t, new(DeviceRepositoryTestSuite))
+}
@@ -1,15 +1,55 @@
 package repositories
 
 import (
+    "errors"
+    "time"
+
     "github.com/connectplus/models"
     "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+)
+
+var (
+    // ErrInvalidTransition is returned when a match can't move from its
+    // current status to the requested one.
+    ErrInvalidTransition = errors.New("invalid match status transition")
+    // ErrNotParticipant is returned when a user acts on a match they are
+    // not part of.
+    ErrNotParticipant = errors.New("user is not part of the match")
+    // ErrAwaitingResponse is returned when the user who completed a match
+    // tries to accept or decline it; only the other participant can.
+    ErrAwaitingResponse = errors.New("match is waiting for the other participant")
+    // ErrAlreadyExtended is returned when a match's one extension has
+    // already been used.
+    ErrAlreadyExtended = errors.New("match was already extended")
 )
 
 type MatchRepository interface {
     Create(match *models.Match) error
+    FindByID(matchID uint) (*models.Match, error)
     FindByUserID(userID uint) ([]models.Match, error)
+    // FindByUserIDAndStatus returns the user's matches in any of the given
+    // statuses, most recently changed first. Matches with inactive users are
+    // left out.
+    FindByUserIDAndStatus(userID uint, statuses ...models.MatchStatus) ([]models.Match, error)
     FindByUsers(user1ID, user2ID uint) (*models.Match, error)
+    // UpdateStatus moves a match to a new status as the system, enforcing
+    // the match state machine and recording the change.
     UpdateStatus(matchID uint, status models.MatchStatus) error
+    // Transition moves a match to a new status on behalf of one of its
+    // participants, enforcing the match state machine and recording the
+    // change. It returns ErrNotParticipant, ErrAwaitingResponse or
+    // ErrInvalidTransition when the change isn't allowed. Unmatching also
+    // hides the pair's messages and clears their swipes on each other.
+    Transition(matchID, actorID uint, status models.MatchStatus) (*models.Match, error)
+    FindEvents(matchID uint) ([]models.MatchEvent, error)
+    // Extend restarts the expiry window of an accepted match on behalf of
+    // one of its participants. Each match can be extended once.
+    Extend(matchID, actorID uint) (*models.Match, error)
+    // ExpireStale moves accepted matches whose expiry window ended before
+    // now without any message between the pair to MatchExpired, returning
+    // how many were expired.
+    ExpireStale(window time.Duration, now time.Time) (int, error)
     Delete(matchID uint) error
 }
 
@@ -25,12 +65,29 @@ func (r *matchRepository) Create(match *models.Match) error {
     return r.db.Create(match).Error
 }
 
+func (r *matchRepository) FindByID(matchID uint) (*models.Match, error) {
+    var match models.Match
+    err := r.db.First(&match, matchID).Error
+    if err != nil {
+        return nil, err
+    }
+    return &match, nil
+}
+
 func (r *matchRepository) FindByUserID(userID uint) ([]models.Match, error) {
     var matches []models.Match
     err := r.db.Where("user1_id = ? OR user2_id = ?", userID, userID).Find(&matches).Error
     return matches, err
 }
 
+func (r *matchRepository) FindByUserIDAndStatus(userID uint, statuses ...models.MatchStatus) ([]models.Match, error) {
+    var matches []models.Match
+    err := r.db.Where("(user1_id = ? OR user2_id = ?) AND status IN ?", userID, userID, statuses).
+        Where("user1_id NOT IN (?) AND user2_id NOT IN (?)", inactiveUserIDs(r.db), inactiveUserIDs(r.db)).
+        Order("updated_at desc, id desc").Find(&matches).Error
+    return matches, err
+}
+
 func (r *matchRepository) FindByUsers(user1ID, user2ID uint) (*models.Match, error) {
     var match models.Match
     err := r.db.Where("(user1_id = ? AND user2_id = ?) OR (user1_id = ? AND user2_id = ?)",
@@ -39,7 +96,172 @@ func (r *matchRepository) FindByUsers(user1ID, user2ID uint) (*models.Match, err
 }
 
 func (r *matchRepository) UpdateStatus(matchID uint, status models.MatchStatus) error {
-    return r.db.Model(&models.Match{}).Where("id = ?", matchID).Update("status", status).Error
+    _, err := r.transition(matchID, nil, status)
+    return err
+}
+
+func (r *matchRepository) Transition(matchID, actorID uint, status models.MatchStatus) (*models.Match, error) {
+    return r.transition(matchID, &actorID, status)
+}
+
+func (r *matchRepository) transition(matchID uint, actorID *uint, status models.MatchStatus) (*models.Match, error) {
+    var match models.Match
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        // Lock the match so concurrent changes are checked against the
+        // status the other one left behind
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&match, matchID).Error; err != nil {
+            return err
+        }
+        if actorID != nil && !match.HasParticipant(*actorID) {
+            return ErrNotParticipant
+        }
+        if !match.Status.CanTransitionTo(status) {
+            return ErrInvalidTransition
+        }
+        if actorID != nil && match.Status == models.MatchPending && !match.CanRespond(*actorID) {
+            return ErrAwaitingResponse
+        }
+
+        from := match.Status
+        updates := map[string]interface{}{"status": status}
+        if status == models.MatchAccepted {
+            now := time.Now()
+            match.AcceptedAt = &now
+            updates["accepted_at"] = now
+        }
+        if status == models.MatchUnmatched {
+            if err := unmatchPair(tx, &match); err != nil {
+                return err
+            }
+            updates["unmatched_at"] = match.UnmatchedAt
+        }
+        match.Status = status
+        if err := tx.Model(&match).Updates(updates).Error; err != nil {
+            return err
+        }
+        return tx.Create(&models.MatchEvent{
+            MatchID:    match.ID,
+            ActorID:    actorID,
+            FromStatus: from,
+            ToStatus:   status,
+        }).Error
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &match, nil
+}
+
+// unmatchPair hides the pair's conversation from both of them and forgets
+// their swipes on each other, so that once the re-match cooldown has passed
+// they can be shown to each other again as if new
+func unmatchPair(tx *gorm.DB, match *models.Match) error {
+    now := time.Now()
+    match.UnmatchedAt = &now
+
+    if err := hideConversation(tx, match.User1ID, match.User2ID, now); err != nil {
+        return err
+    }
+    return tx.Where("(swiper_id = ? AND swiped_id = ?) OR (swiper_id = ? AND swiped_id = ?)",
+        match.User1ID, match.User2ID, match.User2ID, match.User1ID).
+        Delete(&models.Swipe{}).Error
+}
+
+// hideConversation hides every message between the two users from both of
+// them. Hidden messages are kept for moderators.
+func hideConversation(tx *gorm.DB, user1ID, user2ID uint, now time.Time) error {
+    return tx.Model(&models.Message{}).
+        Where("(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)",
+            user1ID, user2ID, user2ID, user1ID).
+        Where("hidden_at IS NULL").
+        Update("hidden_at", now).Error
+}
+
+func (r *matchRepository) FindEvents(matchID uint) ([]models.MatchEvent, error) {
+    var events []models.MatchEvent
+    err := r.db.Where("match_id = ?", matchID).Order("created_at asc, id asc").Find(&events).Error
+    return events, err
+}
+
+func (r *matchRepository) Extend(matchID, actorID uint) (*models.Match, error) {
+    var match models.Match
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&match, matchID).Error; err != nil {
+            return err
+        }
+        if !match.HasParticipant(actorID) {
+            return ErrNotParticipant
+        }
+        if match.Status != models.MatchAccepted {
+            return ErrInvalidTransition
+        }
+        if match.ExtendedAt != nil {
+            return ErrAlreadyExtended
+        }
+
+        now := time.Now()
+        match.ExtendedAt = &now
+        return tx.Model(&match).Update("extended_at", now).Error
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &match, nil
+}
+
+func (r *matchRepository) ExpireStale(window time.Duration, now time.Time) (int, error) {
+    var ids []uint
+    err := r.stale(r.db, window, now).Model(&models.Match{}).Pluck("id", &ids).Error
+    if err != nil {
+        return 0, err
+    }
+
+    expired := 0
+    for _, id := range ids {
+        changed := false
+        err := r.db.Transaction(func(tx *gorm.DB) error {
+            // Check again under lock: the pair may have started talking or
+            // unmatched since the candidates were selected
+            var match models.Match
+            err := r.stale(tx, window, now).Clauses(clause.Locking{Strength: "UPDATE"}).
+                Where("id = ?", id).First(&match).Error
+            if errors.Is(err, gorm.ErrRecordNotFound) {
+                return nil
+            }
+            if err != nil {
+                return err
+            }
+
+            if err := tx.Model(&match).Update("status", models.MatchExpired).Error; err != nil {
+                return err
+            }
+            changed = true
+            return tx.Create(&models.MatchEvent{
+                MatchID:    match.ID,
+                FromStatus: models.MatchAccepted,
+                ToStatus:   models.MatchExpired,
+            }).Error
+        })
+        if err != nil {
+            return expired, err
+        }
+        // Only count the match once its transaction has committed
+        if changed {
+            expired++
+        }
+    }
+    return expired, nil
+}
+
+// stale scopes a query to accepted matches past their expiry window with no
+// visible message between the pair
+func (r *matchRepository) stale(db *gorm.DB, window time.Duration, now time.Time) *gorm.DB {
+    return db.Where("matches.status = ?", models.MatchAccepted).
+        Where("COALESCE(matches.extended_at, matches.accepted_at, matches.created_at) <= ?", now.Add(-window)).
+        Where("NOT EXISTS (?)", db.Session(&gorm.Session{NewDB: true}).Model(&models.Message{}).Select("1").
+            Where("(messages.sender_id = matches.user1_id AND messages.receiver_id = matches.user2_id) OR "+
+                "(messages.sender_id = matches.user2_id AND messages.receiver_id = matches.user1_id)").
+            Where("messages.hidden_at IS NULL"))
 }
 
 func (r *matchRepository) Delete(matchID uint) error {
@@ -2,6 +2,7 @@ package repositories
 
 import (
     "testing"
+    "time"
     
     "github.com/connectplus/models"
     "github.com/stretchr/testify/assert"
@@ -21,8 +22,9 @@ func (suite *MatchRepositoryTestSuite) SetupTest() {
     suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
     assert.NoError(suite.T(), err)
     
-    // Migrate the schema for both User and Match
-    err = suite.db.AutoMigrate(&models.User{}, &models.Match{})
+    // Migrate the schema for User, Match and the match audit trail, plus the
+    // messages and swipes an unmatch touches
+    err = suite.db.AutoMigrate(&models.User{}, &models.Match{}, &models.MatchEvent{}, &models.Message{}, &models.Swipe{})
     assert.NoError(suite.T(), err)
     
     suite.repo = NewMatchRepository(suite.db)
@@ -137,6 +139,236 @@ func (suite *MatchRepositoryTestSuite) TestUpdateStatus() {
     assert.Equal(suite.T(), models.MatchAccepted, updatedMatch.Status)
 }
 
+func (suite *MatchRepositoryTestSuite) TestUpdateStatusRejectsIllegalTransition() {
+    match := &models.Match{User1ID: 1, User2ID: 2, Status: models.MatchDeclined}
+    suite.db.Create(match)
+
+    err := suite.repo.UpdateStatus(match.ID, models.MatchAccepted)
+    assert.ErrorIs(suite.T(), err, ErrInvalidTransition)
+
+    found, err := suite.repo.FindByID(match.ID)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), models.MatchDeclined, found.Status)
+}
+
+func (suite *MatchRepositoryTestSuite) TestTransition() {
+    match := &models.Match{User1ID: 1, User2ID: 2, Status: models.MatchPending}
+    suite.db.Create(match)
+
+    updated, err := suite.repo.Transition(match.ID, 2, models.MatchAccepted)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), models.MatchAccepted, updated.Status)
+    assert.NotNil(suite.T(), updated.AcceptedAt)
+
+    updated, err = suite.repo.Transition(match.ID, 1, models.MatchUnmatched)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), models.MatchUnmatched, updated.Status)
+
+    // Every change is recorded with its actor, in order
+    events, err := suite.repo.FindEvents(match.ID)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), events, 2)
+    assert.Equal(suite.T(), models.MatchPending, events[0].FromStatus)
+    assert.Equal(suite.T(), models.MatchAccepted, events[0].ToStatus)
+    assert.Equal(suite.T(), uint(2), *events[0].ActorID)
+    assert.Equal(suite.T(), models.MatchAccepted, events[1].FromStatus)
+    assert.Equal(suite.T(), models.MatchUnmatched, events[1].ToStatus)
+    assert.Equal(suite.T(), uint(1), *events[1].ActorID)
+    assert.False(suite.T(), events[1].CreatedAt.IsZero())
+}
+
+func (suite *MatchRepositoryTestSuite) TestUnmatchHidesConversationAndClearsSwipes() {
+    match := &models.Match{User1ID: 1, User2ID: 2, Status: models.MatchAccepted}
+    suite.db.Create(match)
+    suite.db.Create(&models.Message{SenderID: 1, ReceiverID: 2, Content: "Hi"})
+    suite.db.Create(&models.Message{SenderID: 2, ReceiverID: 1, Content: "Hey"})
+    suite.db.Create(&models.Message{SenderID: 1, ReceiverID: 3, Content: "Other chat"})
+    suite.db.Create(&models.Swipe{SwiperID: 1, SwipedID: 2, Direction: models.SwipeLike})
+    suite.db.Create(&models.Swipe{SwiperID: 2, SwipedID: 1, Direction: models.SwipeLike})
+    suite.db.Create(&models.Swipe{SwiperID: 1, SwipedID: 3, Direction: models.SwipeLike})
+
+    updated, err := suite.repo.Transition(match.ID, 2, models.MatchUnmatched)
+    assert.NoError(suite.T(), err)
+    assert.NotNil(suite.T(), updated.UnmatchedAt)
+
+    var hidden, visible int64
+    suite.db.Model(&models.Message{}).Where("hidden_at IS NOT NULL").Count(&hidden)
+    suite.db.Model(&models.Message{}).Where("hidden_at IS NULL").Count(&visible)
+    assert.Equal(suite.T(), int64(2), hidden)
+    assert.Equal(suite.T(), int64(1), visible)
+
+    var swipes []models.Swipe
+    suite.db.Find(&swipes)
+    assert.Len(suite.T(), swipes, 1)
+    assert.Equal(suite.T(), uint(3), swipes[0].SwipedID)
+
+    found, err := suite.repo.FindByID(match.ID)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), models.MatchUnmatched, found.Status)
+    assert.NotNil(suite.T(), found.UnmatchedAt)
+}
+
+func (suite *MatchRepositoryTestSuite) TestExpireStale() {
+    now := time.Now()
+    old := now.Add(-100 * time.Hour)
+    recent := now.Add(-time.Hour)
+
+    silent := &models.Match{User1ID: 1, User2ID: 2, Status: models.MatchAccepted, AcceptedAt: &old}
+    talking := &models.Match{User1ID: 1, User2ID: 3, Status: models.MatchAccepted, AcceptedAt: &old}
+    fresh := &models.Match{User1ID: 1, User2ID: 4, Status: models.MatchAccepted, AcceptedAt: &recent}
+    extended := &models.Match{User1ID: 1, User2ID: 5, Status: models.MatchAccepted, AcceptedAt: &old, ExtendedAt: &recent}
+    pending := &models.Match{User1ID: 1, User2ID: 6, Status: models.MatchPending}
+    for _, match := range []*models.Match{silent, talking, fresh, extended, pending} {
+        suite.db.Create(match)
+    }
+    // Only the reply direction has a message; that still counts
+    suite.db.Create(&models.Message{SenderID: 3, ReceiverID: 1, Content: "Hi"})
+
+    expired, err := suite.repo.ExpireStale(72*time.Hour, now)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), 1, expired)
+
+    statuses := map[uint]models.MatchStatus{}
+    for _, match := range []*models.Match{silent, talking, fresh, extended, pending} {
+        found, err := suite.repo.FindByID(match.ID)
+        assert.NoError(suite.T(), err)
+        statuses[found.User2ID] = found.Status
+    }
+    assert.Equal(suite.T(), models.MatchExpired, statuses[2])
+    assert.Equal(suite.T(), models.MatchAccepted, statuses[3])
+    assert.Equal(suite.T(), models.MatchAccepted, statuses[4])
+    assert.Equal(suite.T(), models.MatchAccepted, statuses[5])
+    assert.Equal(suite.T(), models.MatchPending, statuses[6])
+
+    events, err := suite.repo.FindEvents(silent.ID)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), events, 1)
+    assert.Nil(suite.T(), events[0].ActorID)
+    assert.Equal(suite.T(), models.MatchExpired, events[0].ToStatus)
+
+    // Running the sweep again finds nothing new
+    expired, err = suite.repo.ExpireStale(72*time.Hour, now)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), 0, expired)
+}
+
+func (suite *MatchRepositoryTestSuite) TestExtend() {
+    accepted := time.Now().Add(-time.Hour)
+    match := &models.Match{User1ID: 1, User2ID: 2, Status: models.MatchAccepted, AcceptedAt: &accepted}
+    suite.db.Create(match)
+
+    _, err := suite.repo.Extend(match.ID, 3)
+    assert.ErrorIs(suite.T(), err, ErrNotParticipant)
+
+    extended, err := suite.repo.Extend(match.ID, 2)
+    assert.NoError(suite.T(), err)
+    assert.NotNil(suite.T(), extended.ExtendedAt)
+    assert.True(suite.T(), extended.ExpiresAt(time.Hour).After(accepted.Add(time.Hour)))
+
+    _, err = suite.repo.Extend(match.ID, 1)
+    assert.ErrorIs(suite.T(), err, ErrAlreadyExtended)
+
+    pending := &models.Match{User1ID: 1, User2ID: 3, Status: models.MatchPending}
+    suite.db.Create(pending)
+    _, err = suite.repo.Extend(pending.ID, 1)
+    assert.ErrorIs(suite.T(), err, ErrInvalidTransition)
+}
+
+func (suite *MatchRepositoryTestSuite) TestTransitionStateMachine() {
+    cases := []struct {
+        from    models.MatchStatus
+        to      models.MatchStatus
+        allowed bool
+    }{
+        {models.MatchPending, models.MatchAccepted, true},
+        {models.MatchPending, models.MatchDeclined, true},
+        {models.MatchPending, models.MatchUnmatched, false},
+        {models.MatchAccepted, models.MatchUnmatched, true},
+        {models.MatchAccepted, models.MatchExpired, true},
+        {models.MatchExpired, models.MatchAccepted, false},
+        {models.MatchAccepted, models.MatchDeclined, false},
+        {models.MatchAccepted, models.MatchPending, false},
+        {models.MatchDeclined, models.MatchAccepted, false},
+        {models.MatchUnmatched, models.MatchAccepted, false},
+    }
+
+    for i, c := range cases {
+        match := &models.Match{User1ID: 1, User2ID: uint(10 + i), Status: c.from}
+        suite.db.Create(match)
+
+        _, err := suite.repo.Transition(match.ID, 1, c.to)
+        if c.allowed {
+            assert.NoError(suite.T(), err, "%s -> %s", c.from, c.to)
+        } else {
+            assert.ErrorIs(suite.T(), err, ErrInvalidTransition, "%s -> %s", c.from, c.to)
+        }
+    }
+}
+
+func (suite *MatchRepositoryTestSuite) TestTransitionByNonParticipant() {
+    match := &models.Match{User1ID: 1, User2ID: 2, Status: models.MatchPending}
+    suite.db.Create(match)
+
+    _, err := suite.repo.Transition(match.ID, 3, models.MatchAccepted)
+    assert.ErrorIs(suite.T(), err, ErrNotParticipant)
+
+    events, err := suite.repo.FindEvents(match.ID)
+    assert.NoError(suite.T(), err)
+    assert.Empty(suite.T(), events)
+}
+
+func (suite *MatchRepositoryTestSuite) TestTransitionByInitiator() {
+    initiator := uint(1)
+    match := &models.Match{User1ID: 1, User2ID: 2, Status: models.MatchPending, InitiatorID: &initiator}
+    suite.db.Create(match)
+
+    // The user whose like completed the pair waits for the other one
+    for _, status := range []models.MatchStatus{models.MatchAccepted, models.MatchDeclined} {
+        _, err := suite.repo.Transition(match.ID, 1, status)
+        assert.ErrorIs(suite.T(), err, ErrAwaitingResponse)
+    }
+
+    updated, err := suite.repo.Transition(match.ID, 2, models.MatchAccepted)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), models.MatchAccepted, updated.Status)
+}
+
+func (suite *MatchRepositoryTestSuite) TestTransitionNonExistentMatch() {
+    _, err := suite.repo.Transition(999, 1, models.MatchAccepted)
+    assert.ErrorIs(suite.T(), err, gorm.ErrRecordNotFound)
+}
+
+func (suite *MatchRepositoryTestSuite) TestFindByUserIDAndStatus() {
+    suite.db.Create(&models.Match{User1ID: 1, User2ID: 2, Status: models.MatchAccepted})
+    suite.db.Create(&models.Match{User1ID: 3, User2ID: 1, Status: models.MatchPending})
+    suite.db.Create(&models.Match{User1ID: 1, User2ID: 4, Status: models.MatchUnmatched})
+    suite.db.Create(&models.Match{User1ID: 2, User2ID: 3, Status: models.MatchAccepted})
+
+    matches, err := suite.repo.FindByUserIDAndStatus(1, models.MatchPending, models.MatchAccepted)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), matches, 2)
+    for _, match := range matches {
+        assert.True(suite.T(), match.HasParticipant(1))
+        assert.NotEqual(suite.T(), models.MatchUnmatched, match.Status)
+    }
+}
+
+func (suite *MatchRepositoryTestSuite) TestFindByUserIDAndStatusSkipsInactiveUsers() {
+    suspended := &models.User{Email: "suspended@example.com", PasswordHash: "hash"}
+    suite.db.Create(suspended)
+    // IsActive defaults to true in the database, so false must be written
+    // after creation
+    suite.db.Model(suspended).Update("is_active", false)
+
+    suite.db.Create(&models.Match{User1ID: 100, User2ID: suspended.ID, Status: models.MatchAccepted})
+    suite.db.Create(&models.Match{User1ID: 100, User2ID: 101, Status: models.MatchAccepted})
+
+    matches, err := suite.repo.FindByUserIDAndStatus(100, models.MatchAccepted)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), matches, 1)
+    assert.Equal(suite.T(), uint(101), matches[0].OtherUserID(100))
+}
+
 func (suite *MatchRepositoryTestSuite) TestDeleteNonExistentMatch() {
     err := suite.repo.Delete(999)
     assert.Error(suite.T(), err)
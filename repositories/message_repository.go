@@ -1,15 +1,52 @@
 package repositories
 
 import (
+    "time"
+
     "github.com/connectplus/models"
     "gorm.io/gorm"
 )
 
 type MessageRepository interface {
     Create(message *models.Message) error
+    FindByID(messageID uint) (*models.Message, error)
     GetConversation(user1ID, user2ID uint) ([]models.Message, error)
+    // GetConversationIncludingHidden returns every message between two
+    // users, including those hidden by an unmatch, for moderators.
+    GetConversationIncludingHidden(user1ID, user2ID uint) ([]models.Message, error)
+    // FindAllByUserID returns every message the user sent or received,
+    // hidden ones included, oldest first, for data exports.
+    FindAllByUserID(userID uint) ([]models.Message, error)
+    // GetConversationPage returns up to limit messages between two users,
+    // newest first, starting after the cursor when one is given.
+    GetConversationPage(user1ID, user2ID uint, before *MessageCursor, limit int) ([]models.Message, error)
     MarkAsRead(messageID uint) error
     Delete(messageID uint) error
+    ListConversations(userID uint, limit, offset int) ([]ConversationSummary, error)
+    // FindCounterpartsWithMessages returns which of otherIDs have a visible
+    // message with the user in either direction.
+    FindCounterpartsWithMessages(userID uint, otherIDs []uint) ([]uint, error)
+}
+
+// MessageCursor marks a position in a conversation by the (created_at, id)
+// of a message; ID breaks ties between messages sent in the same instant.
+type MessageCursor struct {
+    CreatedAt time.Time
+    ID        uint
+}
+
+// ConversationSummary is one entry of a user's inbox: the other participant,
+// the latest message exchanged with them and how many of their messages the
+// user hasn't read.
+type ConversationSummary struct {
+    CounterpartID uint
+    MessageID     uint
+    SenderID      uint
+    ReceiverID    uint
+    Content       string
+    IsRead        bool
+    CreatedAt     time.Time
+    UnreadCount   int
 }
 
 type messageRepository struct {
@@ -24,10 +61,50 @@ func (r *messageRepository) Create(message *models.Message) error {
     return r.db.Create(message).Error
 }
 
+func (r *messageRepository) FindByID(messageID uint) (*models.Message, error) {
+    var message models.Message
+    err := r.db.First(&message, messageID).Error
+    if err != nil {
+        return nil, err
+    }
+    return &message, nil
+}
+
 func (r *messageRepository) GetConversation(user1ID, user2ID uint) ([]models.Message, error) {
     var messages []models.Message
-    err := r.db.Where("(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)",
-        user1ID, user2ID, user2ID, user1ID).Order("created_at asc").Find(&messages).Error
+    err := r.conversation(user1ID, user2ID).Where("hidden_at IS NULL").Order("created_at asc").Find(&messages).Error
+    return messages, err
+}
+
+func (r *messageRepository) GetConversationIncludingHidden(user1ID, user2ID uint) ([]models.Message, error) {
+    var messages []models.Message
+    err := r.conversation(user1ID, user2ID).Order("created_at asc, id asc").Find(&messages).Error
+    return messages, err
+}
+
+func (r *messageRepository) FindAllByUserID(userID uint) ([]models.Message, error) {
+    var messages []models.Message
+    err := r.db.Where("sender_id = ? OR receiver_id = ?", userID, userID).
+        Order("created_at asc, id asc").
+        Find(&messages).Error
+    return messages, err
+}
+
+// conversation scopes a query to the messages between two users in either
+// direction
+func (r *messageRepository) conversation(user1ID, user2ID uint) *gorm.DB {
+    return r.db.Where("(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)",
+        user1ID, user2ID, user2ID, user1ID)
+}
+
+func (r *messageRepository) GetConversationPage(user1ID, user2ID uint, before *MessageCursor, limit int) ([]models.Message, error) {
+    var messages []models.Message
+    query := r.conversation(user1ID, user2ID).Where("hidden_at IS NULL")
+    if before != nil {
+        query = query.Where("created_at < ? OR (created_at = ? AND id < ?)",
+            before.CreatedAt, before.CreatedAt, before.ID)
+    }
+    err := query.Order("created_at desc, id desc").Limit(limit).Find(&messages).Error
     return messages, err
 }
 
@@ -38,3 +115,46 @@ func (r *messageRepository) MarkAsRead(messageID uint) error {
 func (r *messageRepository) Delete(messageID uint) error {
     return r.db.Delete(&models.Message{}, messageID).Error
 }
+
+// ListConversations returns the user's conversations, most recently active
+// first. The latest message and unread count of every thread are computed
+// with window functions in a single query.
+func (r *messageRepository) ListConversations(userID uint, limit, offset int) ([]ConversationSummary, error) {
+    var summaries []ConversationSummary
+    err := r.db.Raw(`
+        WITH threads AS (
+            SELECT id, sender_id, receiver_id, content, is_read, created_at,
+                CASE WHEN sender_id = @user THEN receiver_id ELSE sender_id END AS counterpart_id
+            FROM messages
+            WHERE (sender_id = @user OR receiver_id = @user) AND hidden_at IS NULL
+        ), ranked AS (
+            SELECT threads.*,
+                ROW_NUMBER() OVER (PARTITION BY counterpart_id ORDER BY created_at DESC, id DESC) AS position,
+                SUM(CASE WHEN receiver_id = @user AND NOT is_read THEN 1 ELSE 0 END)
+                    OVER (PARTITION BY counterpart_id) AS unread_count
+            FROM threads
+        )
+        SELECT counterpart_id, id AS message_id, sender_id, receiver_id, content, is_read, created_at, unread_count
+        FROM ranked
+        WHERE position = 1
+        ORDER BY created_at DESC, message_id DESC
+        LIMIT @limit OFFSET @offset`,
+        map[string]interface{}{"user": userID, "limit": limit, "offset": offset},
+    ).Scan(&summaries).Error
+    return summaries, err
+}
+
+func (r *messageRepository) FindCounterpartsWithMessages(userID uint, otherIDs []uint) ([]uint, error) {
+    var ids []uint
+    if len(otherIDs) == 0 {
+        return ids, nil
+    }
+    err := r.db.Raw(`
+        SELECT DISTINCT CASE WHEN sender_id = @user THEN receiver_id ELSE sender_id END
+        FROM messages
+        WHERE ((sender_id = @user AND receiver_id IN @others) OR (receiver_id = @user AND sender_id IN @others))
+            AND hidden_at IS NULL`,
+        map[string]interface{}{"user": userID, "others": otherIDs},
+    ).Scan(&ids).Error
+    return ids, err
+}
@@ -46,6 +46,22 @@ func (suite *MessageRepositoryTestSuite) TestCreateMessage() {
     assert.NotZero(suite.T(), message.ID)
 }
 
+func (suite *MessageRepositoryTestSuite) TestFindByID() {
+    message := &models.Message{SenderID: 1, ReceiverID: 2, Content: "Test message"}
+    suite.db.Create(message)
+
+    found, err := suite.repo.FindByID(message.ID)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), "Test message", found.Content)
+    assert.Equal(suite.T(), uint(2), found.ReceiverID)
+}
+
+func (suite *MessageRepositoryTestSuite) TestFindNonExistentMessage() {
+    found, err := suite.repo.FindByID(999)
+    assert.Error(suite.T(), err)
+    assert.Nil(suite.T(), found)
+}
+
 func (suite *MessageRepositoryTestSuite) TestGetEmptyConversation() {
     messages, err := suite.repo.GetConversation(1, 2)
     assert.NoError(suite.T(), err)
@@ -113,6 +129,99 @@ func (suite *MessageRepositoryTestSuite) TestGetConversation() {
     assert.Len(suite.T(), conversationReversed, 4)
 }
 
+func (suite *MessageRepositoryTestSuite) TestGetConversationPage() {
+    base := time.Now().Add(-time.Hour)
+    // Two messages share a timestamp so the id tie-break is exercised
+    timestamps := []time.Time{base, base.Add(time.Minute), base.Add(time.Minute), base.Add(2 * time.Minute), base.Add(3 * time.Minute)}
+    for i, ts := range timestamps {
+        sender, receiver := uint(1), uint(2)
+        if i%2 == 1 {
+            sender, receiver = 2, 1
+        }
+        suite.db.Create(&models.Message{SenderID: sender, ReceiverID: receiver, Content: string(rune('a' + i)), CreatedAt: ts})
+    }
+    suite.db.Create(&models.Message{SenderID: 1, ReceiverID: 3, Content: "other", CreatedAt: base.Add(4 * time.Minute)})
+
+    var contents []string
+    var cursor *MessageCursor
+    for {
+        page, err := suite.repo.GetConversationPage(2, 1, cursor, 2)
+        assert.NoError(suite.T(), err)
+        for _, msg := range page {
+            contents = append(contents, msg.Content)
+        }
+        if len(page) < 2 {
+            break
+        }
+        last := page[len(page)-1]
+        cursor = &MessageCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+    }
+
+    // Newest first, every message exactly once
+    assert.Equal(suite.T(), []string{"e", "d", "c", "b", "a"}, contents)
+}
+
+func (suite *MessageRepositoryTestSuite) TestGetConversationPageEmpty() {
+    page, err := suite.repo.GetConversationPage(1, 2, nil, 20)
+    assert.NoError(suite.T(), err)
+    assert.Empty(suite.T(), page)
+}
+
+func (suite *MessageRepositoryTestSuite) TestHiddenMessagesAreExcluded() {
+    hiddenAt := time.Now()
+    suite.db.Create(&models.Message{SenderID: 1, ReceiverID: 2, Content: "Before unmatch", HiddenAt: &hiddenAt})
+    suite.db.Create(&models.Message{SenderID: 1, ReceiverID: 3, Content: "Visible"})
+
+    conversation, err := suite.repo.GetConversation(1, 2)
+    assert.NoError(suite.T(), err)
+    assert.Empty(suite.T(), conversation)
+
+    page, err := suite.repo.GetConversationPage(2, 1, nil, 10)
+    assert.NoError(suite.T(), err)
+    assert.Empty(suite.T(), page)
+
+    conversations, err := suite.repo.ListConversations(1, 10, 0)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), conversations, 1)
+    assert.Equal(suite.T(), uint(3), conversations[0].CounterpartID)
+
+    // Moderators can still see the hidden conversation
+    all, err := suite.repo.GetConversationIncludingHidden(2, 1)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), all, 1)
+    assert.Equal(suite.T(), "Before unmatch", all[0].Content)
+}
+
+func (suite *MessageRepositoryTestSuite) TestFindAllByUserID() {
+    hiddenAt := time.Now()
+    suite.db.Create(&models.Message{SenderID: 1, ReceiverID: 2, Content: "Sent", HiddenAt: &hiddenAt})
+    suite.db.Create(&models.Message{SenderID: 3, ReceiverID: 1, Content: "Received"})
+    suite.db.Create(&models.Message{SenderID: 2, ReceiverID: 3, Content: "Someone else's"})
+
+    messages, err := suite.repo.FindAllByUserID(1)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), messages, 2)
+    assert.Equal(suite.T(), "Sent", messages[0].Content)
+    assert.Equal(suite.T(), "Received", messages[1].Content)
+}
+
+func (suite *MessageRepositoryTestSuite) TestFindCounterpartsWithMessages() {
+    hiddenAt := time.Now()
+    suite.db.Create(&models.Message{SenderID: 1, ReceiverID: 2, Content: "Sent"})
+    suite.db.Create(&models.Message{SenderID: 3, ReceiverID: 1, Content: "Received"})
+    suite.db.Create(&models.Message{SenderID: 3, ReceiverID: 1, Content: "Received again"})
+    suite.db.Create(&models.Message{SenderID: 1, ReceiverID: 4, Content: "Hidden", HiddenAt: &hiddenAt})
+    suite.db.Create(&models.Message{SenderID: 5, ReceiverID: 6, Content: "Unrelated"})
+
+    ids, err := suite.repo.FindCounterpartsWithMessages(1, []uint{2, 3, 4, 5})
+    assert.NoError(suite.T(), err)
+    assert.ElementsMatch(suite.T(), []uint{2, 3}, ids)
+
+    ids, err = suite.repo.FindCounterpartsWithMessages(1, nil)
+    assert.NoError(suite.T(), err)
+    assert.Empty(suite.T(), ids)
+}
+
 func (suite *MessageRepositoryTestSuite) TestMarkNonExistentMessageAsRead() {
     err := suite.repo.MarkAsRead(999)
     assert.Error(suite.T(), err)
@@ -153,6 +262,58 @@ func (suite *MessageRepositoryTestSuite) TestDeleteMessage() {
     assert.Empty(suite.T(), messages)
 }
 
+func (suite *MessageRepositoryTestSuite) TestListConversations() {
+    now := time.Now()
+    messages := []*models.Message{
+        {SenderID: 1, ReceiverID: 2, Content: "Hi 2", CreatedAt: now.Add(-3 * time.Hour)},
+        {SenderID: 2, ReceiverID: 1, Content: "Hey 1", CreatedAt: now.Add(-2 * time.Hour)},
+        {SenderID: 2, ReceiverID: 1, Content: "Still there?", CreatedAt: now.Add(-1 * time.Hour)},
+        {SenderID: 3, ReceiverID: 1, Content: "Hello from 3", CreatedAt: now.Add(-4 * time.Hour), IsRead: true},
+        {SenderID: 1, ReceiverID: 4, Content: "Hi 4", CreatedAt: now},
+        {SenderID: 2, ReceiverID: 3, Content: "Not involving 1", CreatedAt: now},
+    }
+    for _, msg := range messages {
+        suite.db.Create(msg)
+    }
+
+    conversations, err := suite.repo.ListConversations(1, 10, 0)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), conversations, 3)
+
+    // Most recent activity first
+    assert.Equal(suite.T(), uint(4), conversations[0].CounterpartID)
+    assert.Equal(suite.T(), "Hi 4", conversations[0].Content)
+    assert.Equal(suite.T(), 0, conversations[0].UnreadCount)
+
+    assert.Equal(suite.T(), uint(2), conversations[1].CounterpartID)
+    assert.Equal(suite.T(), "Still there?", conversations[1].Content)
+    assert.Equal(suite.T(), uint(2), conversations[1].SenderID)
+    assert.Equal(suite.T(), 2, conversations[1].UnreadCount)
+    assert.WithinDuration(suite.T(), now.Add(-1*time.Hour), conversations[1].CreatedAt, time.Second)
+
+    assert.Equal(suite.T(), uint(3), conversations[2].CounterpartID)
+    assert.Equal(suite.T(), 0, conversations[2].UnreadCount)
+}
+
+func (suite *MessageRepositoryTestSuite) TestListConversationsPagination() {
+    now := time.Now()
+    for i := uint(2); i <= 5; i++ {
+        suite.db.Create(&models.Message{SenderID: 1, ReceiverID: i, Content: "Hi", CreatedAt: now.Add(time.Duration(i) * time.Minute)})
+    }
+
+    page, err := suite.repo.ListConversations(1, 2, 1)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), page, 2)
+    assert.Equal(suite.T(), uint(4), page[0].CounterpartID)
+    assert.Equal(suite.T(), uint(3), page[1].CounterpartID)
+}
+
+func (suite *MessageRepositoryTestSuite) TestListConversationsEmpty() {
+    conversations, err := suite.repo.ListConversations(1, 10, 0)
+    assert.NoError(suite.T(), err)
+    assert.Empty(suite.T(), conversations)
+}
+
 func TestMessageRepositorySuite(t *testing.T) {
     suite.Run(t, new(MessageRepositoryTestSuite))
 }
@@ -0,0 +1,77 @@
+package repositories
+
+import (
+    "time"
+
+    "github.com/connectplus/models"
+    "gorm.io/gorm"
+)
+
+type NotificationRepository interface {
+    Create(notification *models.Notification) error
+    // FindByUserID returns a page of the user's notifications, newest first.
+    FindByUserID(userID uint, limit, offset int) ([]models.Notification, error)
+    // FindAllByUserID returns every notification of the user, oldest
+    // first, for data exports.
+    FindAllByUserID(userID uint) ([]models.Notification, error)
+    CountUnread(userID uint) (int64, error)
+    // MarkRead marks the given notifications of the user read, ignoring IDs
+    // that are someone else's or already read, and returns how many changed.
+    MarkRead(userID uint, notificationIDs []uint) (int64, error)
+    // MarkAllRead marks every unread notification of the user read and
+    // returns how many changed.
+    MarkAllRead(userID uint) (int64, error)
+}
+
+type notificationRepository struct {
+    db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+    return &notificationRepository{db: db}
+}
+
+func (r *notificationRepository) Create(notification *models.Notification) error {
+    return r.db.Create(notification).Error
+}
+
+func (r *notificationRepository) FindByUserID(userID uint, limit, offset int) ([]models.Notification, error) {
+    var notifications []models.Notification
+    err := r.db.Where("user_id = ?", userID).
+        Order("created_at desc, id desc").
+        Limit(limit).Offset(offset).
+        Find(&notifications).Error
+    return notifications, err
+}
+
+func (r *notificationRepository) FindAllByUserID(userID uint) ([]models.Notification, error) {
+    var notifications []models.Notification
+    err := r.db.Where("user_id = ?", userID).
+        Order("created_at asc, id asc").
+        Find(&notifications).Error
+    return notifications, err
+}
+
+func (r *notificationRepository) CountUnread(userID uint) (int64, error) {
+    var count int64
+    err := r.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+    return count, err
+}
+
+func (r *notificationRepository) MarkRead(userID uint, notificationIDs []uint) (int64, error) {
+    if len(notificationIDs) == 0 {
+        return 0, nil
+    }
+    return r.markRead(r.db.Where("id IN ?", notificationIDs), userID)
+}
+
+func (r *notificationRepository) MarkAllRead(userID uint) (int64, error) {
+    return r.markRead(r.db, userID)
+}
+
+func (r *notificationRepository) markRead(query *gorm.DB, userID uint) (int64, error) {
+    result := query.Model(&models.Notification{}).
+        Where("user_id = ? AND read_at IS NULL", userID).
+        Update("read_at", time.Now())
+    return result.RowsAffected, result.Error
+}
@@ -0,0 +1,150 @@
+package repositories
+
+import (
+    "testing"
+    "time"
+
+    "github.com/connectplus/models"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/suite"
+    "gorm.io/driver/sqlite"
+    "gorm.io/gorm"
+)
+
+type NotificationRepositoryTestSuite struct {
+    suite.Suite
+    db   *gorm.DB
+    repo NotificationRepository
+}
+
+func (suite *NotificationRepositoryTestSuite) SetupTest() {
+    var err error
+    suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+    assert.NoError(suite.T(), err)
+
+    err = suite.db.AutoMigrate(&models.User{}, &models.Notification{})
+    assert.NoError(suite.T(), err)
+
+    suite.repo = NewNotificationRepository(suite.db)
+}
+
+func (suite *NotificationRepositoryTestSuite) TearDownTest() {
+    db, _ := suite.db.DB()
+    db.Close()
+}
+
+func (suite *NotificationRepositoryTestSuite) TestCreateNotification() {
+    notification := &models.Notification{
+        UserID: 1,
+        Kind:   models.NotificationNewMatch,
+        Title:  "It's a match!",
+        Body:   "You and Jane liked each other",
+        Data:   map[string]string{"match_id": "12"},
+    }
+    err := suite.repo.Create(notification)
+    assert.NoError(suite.T(), err)
+    assert.NotZero(suite.T(), notification.ID)
+
+    var found models.Notification
+    suite.db.First(&found, notification.ID)
+    assert.Equal(suite.T(), models.NotificationNewMatch, found.Kind)
+    assert.Equal(suite.T(), "12", found.Data["match_id"])
+    assert.Equal(suite.T(), 1, found.Count)
+    assert.Nil(suite.T(), found.ReadAt)
+}
+
+func (suite *NotificationRepositoryTestSuite) createNotifications(userID uint, count int) []models.Notification {
+    notifications := make([]models.Notification, count)
+    base := time.Now().Add(-time.Hour)
+    for i := range notifications {
+        notifications[i] = models.Notification{
+            UserID:    userID,
+            Kind:      models.NotificationNewMessage,
+            Title:     "Jane",
+            CreatedAt: base.Add(time.Duration(i) * time.Minute),
+        }
+        suite.db.Create(&notifications[i])
+    }
+    return notifications
+}
+
+func (suite *NotificationRepositoryTestSuite) TestFindByUserID() {
+    created := suite.createNotifications(1, 3)
+    suite.createNotifications(2, 1)
+
+    notifications, err := suite.repo.FindByUserID(1, 2, 0)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), notifications, 2)
+    assert.Equal(suite.T(), created[2].ID, notifications[0].ID)
+    assert.Equal(suite.T(), created[1].ID, notifications[1].ID)
+
+    notifications, err = suite.repo.FindByUserID(1, 2, 2)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), notifications, 1)
+    assert.Equal(suite.T(), created[0].ID, notifications[0].ID)
+}
+
+func (suite *NotificationRepositoryTestSuite) TestFindAllByUserID() {
+    created := suite.createNotifications(1, 3)
+    suite.createNotifications(2, 1)
+
+    notifications, err := suite.repo.FindAllByUserID(1)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), notifications, 3)
+    assert.Equal(suite.T(), created[0].ID, notifications[0].ID)
+    assert.Equal(suite.T(), created[2].ID, notifications[2].ID)
+}
+
+func (suite *NotificationRepositoryTestSuite) TestMarkRead() {
+    created := suite.createNotifications(1, 3)
+    others := suite.createNotifications(2, 1)
+
+    count, err := suite.repo.CountUnread(1)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), int64(3), count)
+
+    // Another user's notification is left alone
+    changed, err := suite.repo.MarkRead(1, []uint{created[0].ID, created[1].ID, others[0].ID})
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), int64(2), changed)
+
+    // Marking again changes nothing
+    changed, err = suite.repo.MarkRead(1, []uint{created[0].ID})
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), int64(0), changed)
+
+    count, _ = suite.repo.CountUnread(1)
+    assert.Equal(suite.T(), int64(1), count)
+    count, _ = suite.repo.CountUnread(2)
+    assert.Equal(suite.T(), int64(1), count)
+
+    var found models.Notification
+    suite.db.First(&found, created[0].ID)
+    assert.NotNil(suite.T(), found.ReadAt)
+}
+
+func (suite *NotificationRepositoryTestSuite) TestMarkReadWithNoIDs() {
+    suite.createNotifications(1, 1)
+
+    changed, err := suite.repo.MarkRead(1, nil)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), int64(0), changed)
+}
+
+func (suite *NotificationRepositoryTestSuite) TestMarkAllRead() {
+    suite.createNotifications(1, 3)
+    suite.createNotifications(2, 2)
+
+    changed, err := suite.repo.MarkAllRead(1)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), int64(3), changed)
+
+    count, _ := suite.repo.CountUnread(1)
+    assert.Equal(suite.T(), int64(0), count)
+    count, _ = suite.repo.CountUnread(2)
+    assert.Equal(suite.T(), int64(2), count)
+}
+
+func TestNotificationRepositorySuite(t *testing.T) {
+    suite.Run(t, new(NotificationRepositoryTestSuite))
+}
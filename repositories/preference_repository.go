@@ -8,6 +8,9 @@ import (
 type PreferenceRepository interface {
     Create(preference *models.Preference) error
     FindByUserID(userID uint) (*models.Preference, error)
+    // FindByUserIDs returns the preferences of those users who have saved
+    // any, in no particular order.
+    FindByUserIDs(userIDs []uint) ([]models.Preference, error)
     Update(preference *models.Preference) error
     Delete(userID uint) error
 }
@@ -30,6 +33,15 @@ func (r *preferenceRepository) FindByUserID(userID uint) (*models.Preference, er
     return &preference, err
 }
 
+func (r *preferenceRepository) FindByUserIDs(userIDs []uint) ([]models.Preference, error) {
+    var preferences []models.Preference
+    if len(userIDs) == 0 {
+        return preferences, nil
+    }
+    err := r.db.Where("user_id IN ?", userIDs).Find(&preferences).Error
+    return preferences, err
+}
+
 func (r *preferenceRepository) Update(preference *models.Preference) error {
     return r.db.Save(preference).Error
 }
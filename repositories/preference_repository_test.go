@@ -78,6 +78,35 @@ func (suite *PreferenceRepositoryTestSuite) TestFindByUserID() {
     assert.Equal(suite.T(), gorm.ErrRecordNotFound, err)
 }
 
+func (suite *PreferenceRepositoryTestSuite) TestFindByUserIDs() {
+    suite.db.Create(&models.Preference{UserID: 1})
+    suite.db.Create(&models.Preference{UserID: 2})
+    suite.db.Create(&models.Preference{UserID: 3})
+
+    preferences, err := suite.repo.FindByUserIDs([]uint{1, 3, 999})
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), preferences, 2)
+
+    preferences, err = suite.repo.FindByUserIDs(nil)
+    assert.NoError(suite.T(), err)
+    assert.Empty(suite.T(), preferences)
+}
+
+func (suite *PreferenceRepositoryTestSuite) TestUpdateWritesFalseFlags() {
+    preference := &models.Preference{UserID: 1, MatchDistance: 50, MinAge: 18, MaxAge: 99}
+    suite.repo.Create(preference)
+
+    preference.ShowOnlineStatus = false
+    preference.ShowDistance = false
+    assert.NoError(suite.T(), suite.repo.Update(preference))
+
+    found, err := suite.repo.FindByUserID(1)
+    assert.NoError(suite.T(), err)
+    assert.False(suite.T(), found.ShowOnlineStatus)
+    assert.True(suite.T(), found.ShowLastActive)
+    assert.False(suite.T(), found.ShowDistance)
+}
+
 func (suite *PreferenceRepositoryTestSuite) TestCreateDuplicatePreference() {
     // Create initial preference
     pref1 := &models.Preference{
@@ -1,15 +1,49 @@
 package repositories
 
 import (
+    "sort"
+    "time"
+
+    "github.com/connectplus/geo"
     "github.com/connectplus/models"
     "gorm.io/gorm"
+    "gorm.io/gorm/clause"
 )
 
 type ProfileRepository interface {
     Create(profile *models.Profile) error
     FindByUserID(userID uint) (*models.Profile, error)
+    // FindActiveByUserID is FindByUserID for profiles other users may see,
+    // returning gorm.ErrRecordNotFound while the owner is suspended or has
+    // deactivated their account.
+    FindActiveByUserID(userID uint) (*models.Profile, error)
+    FindByUserIDs(userIDs []uint) ([]models.Profile, error)
     Update(profile *models.Profile) error
+    // UpdateColumns saves only the named columns of the profile, leaving
+    // any other column changed concurrently untouched.
+    UpdateColumns(profile *models.Profile, columns ...string) error
+    // UpdatePhotos replaces the user's photo list with what update returns
+    // for the current one, holding a lock on the profile in between so
+    // concurrent changes can't overwrite each other. An error from update
+    // aborts the change and is returned as is.
+    UpdatePhotos(userID uint, update func(photos []string) ([]string, error)) (*models.Profile, error)
     Delete(userID uint) error
+    Discover(filter DiscoveryFilter) ([]models.Profile, error)
+    UpdateLocation(userID uint, latitude, longitude float64) error
+    FindWithinRadius(latitude, longitude, radiusKm float64) ([]models.Profile, error)
+}
+
+// DiscoveryFilter narrows the candidate profiles shown to a user.
+type DiscoveryFilter struct {
+    UserID          uint // the user browsing; excluded along with users they swiped on, matched or blocked
+    MinAge          int
+    MaxAge          int
+    MaxDistanceKm   int
+    Latitude        *float64 // the browsing user's position; distance is only applied when set
+    Longitude       *float64
+    Limit           int
+    Offset          int
+    RematchCooldown time.Duration // how long a pair who unmatched stay hidden from each other
 }
 
 type profileRepository struct {
@@ -30,10 +64,182 @@ func (r *profileRepository) FindByUserID(userID uint) (*models.Profile, error) {
     return &profile, err
 }
 
+func (r *profileRepository) FindActiveByUserID(userID uint) (*models.Profile, error) {
+    var profile models.Profile
+    err := r.db.Where("user_id = ?", userID).
+        Where("user_id NOT IN (?)", inactiveUserIDs(r.db)).
+        First(&profile).Error
+    return &profile, err
+}
+
+func (r *profileRepository) FindByUserIDs(userIDs []uint) ([]models.Profile, error) {
+    var profiles []models.Profile
+    if len(userIDs) == 0 {
+        return profiles, nil
+    }
+    err := r.db.Where("user_id IN ?", userIDs).Find(&profiles).Error
+    return profiles, err
+}
+
 func (r *profileRepository) Update(profile *models.Profile) error {
     return r.db.Save(profile).Error
 }
 
+func (r *profileRepository) UpdateColumns(profile *models.Profile, columns ...string) error {
+    if len(columns) == 0 {
+        return nil
+    }
+    result := r.db.Model(profile).Select(columns).Updates(profile)
+    if result.Error != nil {
+        return result.Error
+    }
+    if result.RowsAffected == 0 {
+        return gorm.ErrRecordNotFound
+    }
+    return nil
+}
+
+func (r *profileRepository) UpdatePhotos(userID uint, update func(photos []string) ([]string, error)) (*models.Profile, error) {
+    var profile models.Profile
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+            Where("user_id = ?", userID).First(&profile).Error; err != nil {
+            return err
+        }
+        photos, err := update(profile.Photos)
+        if err != nil {
+            return err
+        }
+        profile.Photos = photos
+        return tx.Model(&profile).Select("photos", "updated_at").Updates(&profile).Error
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &profile, nil
+}
+
 func (r *profileRepository) Delete(userID uint) error {
     return r.db.Where("user_id = ?", userID).Delete(&models.Profile{}).Error
 }
+
+// Discover returns profiles the filter's user has not yet swiped on or
+// matched with, or unmatched from within the re-match cooldown, within the
+// requested age range. Inactive users and users blocked by or blocking the
+// filter's user are never returned. When the filter carries a position and
+// MaxDistanceKm, only profiles within that distance are returned, nearest
+// first.
+func (r *profileRepository) Discover(filter DiscoveryFilter) ([]models.Profile, error) {
+    now := time.Now()
+    query := r.db.Model(&models.Profile{}).
+        Where("user_id <> ?", filter.UserID).
+        Where("user_id NOT IN (?)", r.db.Model(&models.Swipe{}).Select("swiped_id").Where("swiper_id = ?", filter.UserID)).
+        Where("user_id NOT IN (?)", r.blockingMatches(filter, now).Select("user2_id").Where("user1_id = ?", filter.UserID)).
+        Where("user_id NOT IN (?)", r.blockingMatches(filter, now).Select("user1_id").Where("user2_id = ?", filter.UserID)).
+        Where("user_id NOT IN (?)", r.db.Model(&models.Block{}).Select("blocked_id").Where("blocker_id = ?", filter.UserID)).
+        Where("user_id NOT IN (?)", r.db.Model(&models.Block{}).Select("blocker_id").Where("blocked_id = ?", filter.UserID)).
+        Where("user_id NOT IN (?)", inactiveUserIDs(r.db))
+
+    // Someone is at least MinAge if born on or before now minus MinAge years,
+    // and at most MaxAge if born after now minus MaxAge+1 years.
+    if filter.MinAge > 0 {
+        query = query.Where("birth_date <= ?", now.AddDate(-filter.MinAge, 0, 0))
+    }
+    if filter.MaxAge > 0 {
+        query = query.Where("birth_date > ?", now.AddDate(-(filter.MaxAge + 1), 0, 0))
+    }
+
+    if filter.Latitude != nil && filter.Longitude != nil && filter.MaxDistanceKm > 0 {
+        profiles, err := withinRadius(query, *filter.Latitude, *filter.Longitude, float64(filter.MaxDistanceKm))
+        if err != nil {
+            return nil, err
+        }
+        return paginate(profiles, filter.Limit, filter.Offset), nil
+    }
+
+    if filter.Limit > 0 {
+        query = query.Limit(filter.Limit)
+    }
+    if filter.Offset > 0 {
+        query = query.Offset(filter.Offset)
+    }
+
+    var profiles []models.Profile
+    err := query.Order("updated_at desc").Order("id desc").Find(&profiles).Error
+    return profiles, err
+}
+
+// blockingMatches selects the matches that keep a pair out of each other's
+// discovery feed: all of them except unmatches older than the cooldown
+func (r *profileRepository) blockingMatches(filter DiscoveryFilter, now time.Time) *gorm.DB {
+    return r.db.Model(&models.Match{}).
+        Where("status <> ? OR unmatched_at IS NULL OR unmatched_at > ?",
+            models.MatchUnmatched, now.Add(-filter.RematchCooldown))
+}
+
+// inactiveUserIDs selects the IDs of suspended and deactivated users
+func inactiveUserIDs(db *gorm.DB) *gorm.DB {
+    return db.Model(&models.User{}).Select("id").Where("is_active = ?", false)
+}
+
+func (r *profileRepository) UpdateLocation(userID uint, latitude, longitude float64) error {
+    result := r.db.Model(&models.Profile{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+        "latitude":            latitude,
+        "longitude":           longitude,
+        "location_updated_at": time.Now(),
+    })
+    if result.Error != nil {
+        return result.Error
+    }
+    if result.RowsAffected == 0 {
+        return gorm.ErrRecordNotFound
+    }
+    return nil
+}
+
+// FindWithinRadius returns every profile within radiusKm of the given
+// position, nearest first.
+func (r *profileRepository) FindWithinRadius(latitude, longitude, radiusKm float64) ([]models.Profile, error) {
+    return withinRadius(r.db.Model(&models.Profile{}), latitude, longitude, radiusKm)
+}
+
+// withinRadius narrows query to a bounding box the database can answer from
+// the latitude/longitude index, then applies the exact haversine distance in
+// Go. SQLite has no trigonometric functions by default, so the distance
+// itself cannot be computed in SQL portably.
+func withinRadius(query *gorm.DB, latitude, longitude, radiusKm float64) ([]models.Profile, error) {
+    box := geo.NewBoundingBox(latitude, longitude, radiusKm)
+    var candidates []models.Profile
+    err := query.
+        Where("latitude BETWEEN ? AND ?", box.MinLat, box.MaxLat).
+        Where("longitude BETWEEN ? AND ?", box.MinLon, box.MaxLon).
+        Find(&candidates).Error
+    if err != nil {
+        return nil, err
+    }
+
+    profiles := make([]models.Profile, 0, len(candidates))
+    distances := make(map[uint]float64, len(candidates))
+    for _, p := range candidates {
+        d := geo.Distance(latitude, longitude, *p.Latitude, *p.Longitude)
+        if d <= radiusKm {
+            profiles = append(profiles, p)
+            distances[p.ID] = d
+        }
+    }
+    sort.SliceStable(profiles, func(i, j int) bool {
+        return distances[profiles[i].ID] < distances[profiles[j].ID]
+    })
+    return profiles, nil
+}
+
+func paginate(profiles []models.Profile, limit, offset int) []models.Profile {
+    if offset >= len(profiles) {
+        return []models.Profile{}
+    }
+    profiles = profiles[offset:]
+    if limit > 0 && limit < len(profiles) {
+        profiles = profiles[:limit]
+    }
+    return profiles
+}
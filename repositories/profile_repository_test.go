@@ -1,7 +1,9 @@
 package repositories
 
 import (
+    "errors"
     "testing"
+    "time"
     
     "github.com/connectplus/models"
     "github.com/stretchr/testify/assert"
@@ -21,8 +23,8 @@ func (suite *ProfileRepositoryTestSuite) SetupTest() {
     suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
     assert.NoError(suite.T(), err)
     
-    // Migrate the schema for both User and Profile
-    err = suite.db.AutoMigrate(&models.User{}, &models.Profile{})
+    // Migrate the schema for User and Profile, plus Swipe and Match for discovery
+    err = suite.db.AutoMigrate(&models.User{}, &models.Profile{}, &models.Swipe{}, &models.Match{}, &models.Block{})
     assert.NoError(suite.T(), err)
     
     suite.repo = NewProfileRepository(suite.db)
@@ -66,6 +68,40 @@ func (suite *ProfileRepositoryTestSuite) TestFindByUserID() {
     assert.Equal(suite.T(), gorm.ErrRecordNotFound, err)
 }
 
+func (suite *ProfileRepositoryTestSuite) TestFindActiveByUserID() {
+    active := &models.User{Email: "active@example.com", PasswordHash: "hash"}
+    suspended := &models.User{Email: "suspended@example.com", PasswordHash: "hash"}
+    suite.db.Create(active)
+    suite.db.Create(suspended)
+    suite.db.Model(suspended).Update("is_active", false)
+    suite.db.Create(&models.Profile{UserID: active.ID, DisplayName: "Active"})
+    suite.db.Create(&models.Profile{UserID: suspended.ID, DisplayName: "Suspended"})
+
+    profile, err := suite.repo.FindActiveByUserID(active.ID)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), "Active", profile.DisplayName)
+
+    _, err = suite.repo.FindActiveByUserID(suspended.ID)
+    assert.Equal(suite.T(), gorm.ErrRecordNotFound, err)
+
+    _, err = suite.repo.FindActiveByUserID(999)
+    assert.Equal(suite.T(), gorm.ErrRecordNotFound, err)
+}
+
+func (suite *ProfileRepositoryTestSuite) TestFindByUserIDs() {
+    suite.db.Create(&models.Profile{UserID: 1, DisplayName: "One"})
+    suite.db.Create(&models.Profile{UserID: 2, DisplayName: "Two"})
+    suite.db.Create(&models.Profile{UserID: 3, DisplayName: "Three"})
+
+    profiles, err := suite.repo.FindByUserIDs([]uint{1, 3, 999})
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), profiles, 2)
+
+    profiles, err = suite.repo.FindByUserIDs(nil)
+    assert.NoError(suite.T(), err)
+    assert.Empty(suite.T(), profiles)
+}
+
 func (suite *ProfileRepositoryTestSuite) TestCreateDuplicateProfile() {
     // Create initial profile
     profile1 := &models.Profile{
@@ -117,6 +153,69 @@ func (suite *ProfileRepositoryTestSuite) TestUpdateProfile() {
     assert.Equal(suite.T(), "Updated bio", updatedProfile.Bio)
 }
 
+func (suite *ProfileRepositoryTestSuite) TestUpdateColumns() {
+    profile := &models.Profile{UserID: 1, DisplayName: "Before", Bio: "Old bio"}
+    suite.db.Create(profile)
+    
+    // A concurrent location update must survive a partial update made from a
+    // stale copy of the profile
+    assert.NoError(suite.T(), suite.repo.UpdateLocation(1, 10, 20))
+    
+    profile.DisplayName = "After"
+    profile.Bio = "Not saved"
+    err := suite.repo.UpdateColumns(profile, "display_name", "updated_at")
+    assert.NoError(suite.T(), err)
+    
+    updatedProfile, err := suite.repo.FindByUserID(1)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), "After", updatedProfile.DisplayName)
+    assert.Equal(suite.T(), "Old bio", updatedProfile.Bio)
+    assert.True(suite.T(), updatedProfile.HasCoordinates())
+}
+
+func (suite *ProfileRepositoryTestSuite) TestUpdateColumnsNonExistentProfile() {
+    profile := &models.Profile{ID: 999, UserID: 999, DisplayName: "Nobody"}
+    err := suite.repo.UpdateColumns(profile, "display_name")
+    assert.Equal(suite.T(), gorm.ErrRecordNotFound, err)
+}
+
+func (suite *ProfileRepositoryTestSuite) TestUpdatePhotos() {
+    suite.db.Create(&models.Profile{UserID: 1, DisplayName: "Jane", Photos: []string{"a"}})
+    // A concurrent bio change made from another copy must survive
+    suite.db.Model(&models.Profile{}).Where("user_id = ?", 1).Update("bio", "New bio")
+
+    profile, err := suite.repo.UpdatePhotos(1, func(photos []string) ([]string, error) {
+        assert.Equal(suite.T(), []string{"a"}, photos)
+        return append(photos, "b"), nil
+    })
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), []string{"a", "b"}, profile.Photos)
+
+    found, _ := suite.repo.FindByUserID(1)
+    assert.Equal(suite.T(), []string{"a", "b"}, found.Photos)
+    assert.Equal(suite.T(), "New bio", found.Bio)
+}
+
+func (suite *ProfileRepositoryTestSuite) TestUpdatePhotosAborted() {
+    suite.db.Create(&models.Profile{UserID: 1, DisplayName: "Jane", Photos: []string{"a"}})
+    errFull := errors.New("full")
+
+    _, err := suite.repo.UpdatePhotos(1, func(photos []string) ([]string, error) {
+        return nil, errFull
+    })
+    assert.ErrorIs(suite.T(), err, errFull)
+
+    found, _ := suite.repo.FindByUserID(1)
+    assert.Equal(suite.T(), []string{"a"}, found.Photos)
+}
+
+func (suite *ProfileRepositoryTestSuite) TestUpdatePhotosNonExistentProfile() {
+    _, err := suite.repo.UpdatePhotos(999, func(photos []string) ([]string, error) {
+        return photos, nil
+    })
+    assert.ErrorIs(suite.T(), err, gorm.ErrRecordNotFound)
+}
+
 func (suite *ProfileRepositoryTestSuite) TestDeleteNonExistentProfile() {
     err := suite.repo.Delete(999) // Non-existent user ID
     assert.Error(suite.T(), err)
@@ -140,6 +239,201 @@ func (suite *ProfileRepositoryTestSuite) TestDeleteProfile() {
     assert.Error(suite.T(), err)
 }
 
+func (suite *ProfileRepositoryTestSuite) TestDiscoverFiltersByAge() {
+    now := time.Now()
+    suite.db.Create(&models.Profile{UserID: 1, DisplayName: "Me", BirthDate: now.AddDate(-30, 0, 0)})
+    suite.db.Create(&models.Profile{UserID: 2, DisplayName: "Too young", BirthDate: now.AddDate(-20, 0, 0)})
+    suite.db.Create(&models.Profile{UserID: 3, DisplayName: "In range", BirthDate: now.AddDate(-28, 0, 0)})
+    suite.db.Create(&models.Profile{UserID: 4, DisplayName: "Just turned max", BirthDate: now.AddDate(-35, 0, -1)})
+    suite.db.Create(&models.Profile{UserID: 5, DisplayName: "Too old", BirthDate: now.AddDate(-36, 0, -1)})
+    
+    profiles, err := suite.repo.Discover(DiscoveryFilter{UserID: 1, MinAge: 25, MaxAge: 35})
+    assert.NoError(suite.T(), err)
+    
+    var userIDs []uint
+    for _, p := range profiles {
+        userIDs = append(userIDs, p.UserID)
+    }
+    assert.ElementsMatch(suite.T(), []uint{3, 4}, userIDs)
+}
+
+func (suite *ProfileRepositoryTestSuite) TestDiscoverExcludesSwipedAndMatched() {
+    birthDate := time.Now().AddDate(-30, 0, 0)
+    for userID := uint(1); userID <= 5; userID++ {
+        suite.db.Create(&models.Profile{UserID: userID, DisplayName: "User", BirthDate: birthDate})
+    }
+    
+    // User 1 passed on user 2 and is matched with users 3 and 4 in both orders
+    suite.db.Create(&models.Swipe{SwiperID: 1, SwipedID: 2, Direction: models.SwipePass})
+    suite.db.Create(&models.Match{User1ID: 1, User2ID: 3, Status: models.MatchAccepted})
+    suite.db.Create(&models.Match{User1ID: 4, User2ID: 1, Status: models.MatchAccepted})
+    
+    // Swipes by other users do not affect user 1's feed
+    suite.db.Create(&models.Swipe{SwiperID: 5, SwipedID: 1, Direction: models.SwipeLike})
+    
+    profiles, err := suite.repo.Discover(DiscoveryFilter{UserID: 1, MinAge: 18, MaxAge: 99})
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), profiles, 1)
+    assert.Equal(suite.T(), uint(5), profiles[0].UserID)
+}
+
+func (suite *ProfileRepositoryTestSuite) TestDiscoverRematchCooldown() {
+    birthDate := time.Now().AddDate(-30, 0, 0)
+    for userID := uint(1); userID <= 3; userID++ {
+        suite.db.Create(&models.Profile{UserID: userID, DisplayName: "User", BirthDate: birthDate})
+    }
+
+    // User 1 unmatched user 2 an hour ago and user 3 two days ago
+    recently := time.Now().Add(-time.Hour)
+    longAgo := time.Now().Add(-48 * time.Hour)
+    suite.db.Create(&models.Match{User1ID: 1, User2ID: 2, Status: models.MatchUnmatched, UnmatchedAt: &recently})
+    suite.db.Create(&models.Match{User1ID: 3, User2ID: 1, Status: models.MatchUnmatched, UnmatchedAt: &longAgo})
+
+    profiles, err := suite.repo.Discover(DiscoveryFilter{UserID: 1, RematchCooldown: 24 * time.Hour})
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), profiles, 1)
+    assert.Equal(suite.T(), uint(3), profiles[0].UserID)
+}
+
+func (suite *ProfileRepositoryTestSuite) TestDiscoverExcludesBlocked() {
+    birthDate := time.Now().AddDate(-30, 0, 0)
+    for userID := uint(1); userID <= 4; userID++ {
+        suite.db.Create(&models.Profile{UserID: userID, DisplayName: "User", BirthDate: birthDate})
+    }
+
+    // User 1 blocked user 2 and was blocked by user 3
+    suite.db.Create(&models.Block{BlockerID: 1, BlockedID: 2})
+    suite.db.Create(&models.Block{BlockerID: 3, BlockedID: 1})
+
+    profiles, err := suite.repo.Discover(DiscoveryFilter{UserID: 1})
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), profiles, 1)
+    assert.Equal(suite.T(), uint(4), profiles[0].UserID)
+
+    profiles, err = suite.repo.Discover(DiscoveryFilter{UserID: 2})
+    assert.NoError(suite.T(), err)
+    for _, profile := range profiles {
+        assert.NotEqual(suite.T(), uint(1), profile.UserID)
+    }
+}
+
+func (suite *ProfileRepositoryTestSuite) TestDiscoverExcludesInactiveUsers() {
+    birthDate := time.Now().AddDate(-30, 0, 0)
+    active := &models.User{Email: "active@example.com", PasswordHash: "hash"}
+    suspended := &models.User{Email: "suspended@example.com", PasswordHash: "hash"}
+    suite.db.Create(active)
+    suite.db.Create(suspended)
+    suite.db.Model(suspended).Update("is_active", false)
+    suite.db.Create(&models.Profile{UserID: active.ID, DisplayName: "Active", BirthDate: birthDate})
+    suite.db.Create(&models.Profile{UserID: suspended.ID, DisplayName: "Suspended", BirthDate: birthDate})
+
+    profiles, err := suite.repo.Discover(DiscoveryFilter{UserID: 999})
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), profiles, 1)
+    assert.Equal(suite.T(), active.ID, profiles[0].UserID)
+}
+
+func (suite *ProfileRepositoryTestSuite) TestDiscoverPagination() {
+    birthDate := time.Now().AddDate(-30, 0, 0)
+    for userID := uint(2); userID <= 6; userID++ {
+        suite.db.Create(&models.Profile{UserID: userID, DisplayName: "User", BirthDate: birthDate})
+    }
+    
+    firstPage, err := suite.repo.Discover(DiscoveryFilter{UserID: 1, Limit: 3})
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), firstPage, 3)
+    
+    secondPage, err := suite.repo.Discover(DiscoveryFilter{UserID: 1, Limit: 3, Offset: 3})
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), secondPage, 2)
+    assert.NotEqual(suite.T(), firstPage[0].UserID, secondPage[0].UserID)
+}
+
+func (suite *ProfileRepositoryTestSuite) TestUpdateLocation() {
+    profile := &models.Profile{UserID: 1, DisplayName: "User"}
+    suite.db.Create(profile)
+    
+    err := suite.repo.UpdateLocation(1, 37.7749, -122.4194)
+    assert.NoError(suite.T(), err)
+    
+    updatedProfile, err := suite.repo.FindByUserID(1)
+    assert.NoError(suite.T(), err)
+    assert.True(suite.T(), updatedProfile.HasCoordinates())
+    assert.Equal(suite.T(), 37.7749, *updatedProfile.Latitude)
+    assert.Equal(suite.T(), -122.4194, *updatedProfile.Longitude)
+    assert.NotNil(suite.T(), updatedProfile.LocationUpdatedAt)
+}
+
+func (suite *ProfileRepositoryTestSuite) TestUpdateLocationNonExistentProfile() {
+    err := suite.repo.UpdateLocation(999, 0, 0)
+    assert.Error(suite.T(), err)
+    assert.Equal(suite.T(), gorm.ErrRecordNotFound, err)
+}
+
+func (suite *ProfileRepositoryTestSuite) createProfileAt(userID uint, lat, lon float64) {
+    suite.db.Create(&models.Profile{
+        UserID:      userID,
+        DisplayName: "User",
+        BirthDate:   time.Now().AddDate(-30, 0, 0),
+        Latitude:    &lat,
+        Longitude:   &lon,
+    })
+}
+
+func (suite *ProfileRepositoryTestSuite) TestFindWithinRadius() {
+    // San Francisco, Oakland (~13 km), San Jose (~68 km), Los Angeles (~559 km)
+    suite.createProfileAt(1, 37.7749, -122.4194)
+    suite.createProfileAt(2, 37.8044, -122.2712)
+    suite.createProfileAt(3, 37.3382, -121.8863)
+    suite.createProfileAt(4, 34.0522, -118.2437)
+    // Profiles without coordinates are never returned
+    suite.db.Create(&models.Profile{UserID: 5, DisplayName: "Nowhere"})
+    
+    profiles, err := suite.repo.FindWithinRadius(37.7749, -122.4194, 50)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), profiles, 2)
+    assert.Equal(suite.T(), uint(1), profiles[0].UserID)
+    assert.Equal(suite.T(), uint(2), profiles[1].UserID)
+    
+    profiles, err = suite.repo.FindWithinRadius(37.7749, -122.4194, 100)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), profiles, 3)
+    assert.Equal(suite.T(), uint(3), profiles[2].UserID)
+}
+
+func (suite *ProfileRepositoryTestSuite) TestDiscoverFiltersByDistance() {
+    suite.createProfileAt(1, 37.7749, -122.4194)
+    suite.createProfileAt(2, 37.3382, -121.8863)
+    suite.createProfileAt(3, 37.8044, -122.2712)
+    suite.createProfileAt(4, 34.0522, -118.2437)
+    
+    lat, lon := 37.7749, -122.4194
+    profiles, err := suite.repo.Discover(DiscoveryFilter{
+        UserID:        1,
+        MaxDistanceKm: 100,
+        Latitude:      &lat,
+        Longitude:     &lon,
+    })
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), profiles, 2)
+    // Nearest first
+    assert.Equal(suite.T(), uint(3), profiles[0].UserID)
+    assert.Equal(suite.T(), uint(2), profiles[1].UserID)
+    
+    // Pagination applies after the distance filter
+    profiles, err = suite.repo.Discover(DiscoveryFilter{
+        UserID:        1,
+        MaxDistanceKm: 100,
+        Latitude:      &lat,
+        Longitude:     &lon,
+        Limit:         1,
+        Offset:        1,
+    })
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), profiles, 1)
+    assert.Equal(suite.T(), uint(2), profiles[0].UserID)
+}
+
 func TestProfileRepositorySuite(t *testing.T) {
     suite.Run(t, new(ProfileRepositoryTestSuite))
 }
@@ -0,0 +1,80 @@
+package repositories
+
+import (
+    "time"
+
+    "github.com/connectplus/models"
+    "gorm.io/gorm"
+)
+
+type ReportRepository interface {
+    Create(report *models.Report) error
+    FindByID(reportID uint) (*models.Report, error)
+    // FindByReporterID returns the reports a user has filed, oldest first.
+    FindByReporterID(reporterID uint) ([]models.Report, error)
+    // FindByStatus returns reports in the given status oldest first, so the
+    // moderation queue is worked through in the order reports arrived.
+    FindByStatus(status models.ReportStatus, limit, offset int) ([]models.Report, error)
+    // Review closes an open report as resolved or dismissed by a moderator.
+    // It returns gorm.ErrRecordNotFound if there is no open report with
+    // that ID.
+    Review(reportID, reviewerID uint, status models.ReportStatus) (*models.Report, error)
+}
+
+type reportRepository struct {
+    db *gorm.DB
+}
+
+func NewReportRepository(db *gorm.DB) ReportRepository {
+    return &reportRepository{db: db}
+}
+
+func (r *reportRepository) Create(report *models.Report) error {
+    return r.db.Create(report).Error
+}
+
+func (r *reportRepository) FindByID(reportID uint) (*models.Report, error) {
+    var report models.Report
+    err := r.db.First(&report, reportID).Error
+    if err != nil {
+        return nil, err
+    }
+    return &report, nil
+}
+
+func (r *reportRepository) FindByReporterID(reporterID uint) ([]models.Report, error) {
+    var reports []models.Report
+    err := r.db.Where("reporter_id = ?", reporterID).Order("created_at asc, id asc").Find(&reports).Error
+    return reports, err
+}
+
+func (r *reportRepository) FindByStatus(status models.ReportStatus, limit, offset int) ([]models.Report, error) {
+    var reports []models.Report
+    query := r.db.Where("status = ?", status).Order("created_at asc, id asc")
+    if limit > 0 {
+        query = query.Limit(limit)
+    }
+    if offset > 0 {
+        query = query.Offset(offset)
+    }
+    err := query.Find(&reports).Error
+    return reports, err
+}
+
+func (r *reportRepository) Review(reportID, reviewerID uint, status models.ReportStatus) (*models.Report, error) {
+    now := time.Now()
+    result := r.db.Model(&models.Report{}).
+        Where("id = ? AND status = ?", reportID, models.ReportOpen).
+        Updates(map[string]interface{}{
+            "status":      status,
+            "reviewer_id": reviewerID,
+            "reviewed_at": now,
+        })
+    if result.Error != nil {
+        return nil, result.Error
+    }
+    if result.RowsAffected == 0 {
+        return nil, gorm.ErrRecordNotFound
+    }
+    return r.FindByID(reportID)
+}
@@ -0,0 +1,108 @@
+package repositories
+
+import (
+    "testing"
+
+    "github.com/connectplus/models"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/suite"
+    "gorm.io/driver/sqlite"
+    "gorm.io/gorm"
+)
+
+type ReportRepositoryTestSuite struct {
+    suite.Suite
+    db   *gorm.DB
+    repo ReportRepository
+}
+
+func (suite *ReportRepositoryTestSuite) SetupTest() {
+    var err error
+    suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+    assert.NoError(suite.T(), err)
+
+    err = suite.db.AutoMigrate(&models.User{}, &models.Report{})
+    assert.NoError(suite.T(), err)
+
+    suite.repo = NewReportRepository(suite.db)
+}
+
+func (suite *ReportRepositoryTestSuite) TearDownTest() {
+    db, _ := suite.db.DB()
+    db.Close()
+}
+
+func (suite *ReportRepositoryTestSuite) TestCreateReport() {
+    report := &models.Report{ReporterID: 1, ReportedID: 2, Reason: models.ReportSpam, Details: "Sends links"}
+    err := suite.repo.Create(report)
+    assert.NoError(suite.T(), err)
+    assert.NotZero(suite.T(), report.ID)
+
+    found, err := suite.repo.FindByID(report.ID)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), models.ReportOpen, found.Status)
+    assert.Equal(suite.T(), models.ReportSpam, found.Reason)
+    assert.Equal(suite.T(), "Sends links", found.Details)
+}
+
+func (suite *ReportRepositoryTestSuite) TestFindNonExistentReport() {
+    _, err := suite.repo.FindByID(999)
+    assert.ErrorIs(suite.T(), err, gorm.ErrRecordNotFound)
+}
+
+func (suite *ReportRepositoryTestSuite) TestFindByReporterID() {
+    suite.repo.Create(&models.Report{ReporterID: 1, ReportedID: 2, Reason: models.ReportSpam})
+    suite.repo.Create(&models.Report{ReporterID: 2, ReportedID: 1, Reason: models.ReportOther})
+    suite.repo.Create(&models.Report{ReporterID: 1, ReportedID: 3, Reason: models.ReportFakeProfile})
+
+    reports, err := suite.repo.FindByReporterID(1)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), reports, 2)
+    assert.Equal(suite.T(), uint(2), reports[0].ReportedID)
+    assert.Equal(suite.T(), uint(3), reports[1].ReportedID)
+}
+
+func (suite *ReportRepositoryTestSuite) TestFindByStatusOldestFirst() {
+    for reporterID := uint(1); reporterID <= 3; reporterID++ {
+        suite.repo.Create(&models.Report{ReporterID: reporterID, ReportedID: 9, Reason: models.ReportHarassment})
+    }
+    suite.repo.Create(&models.Report{ReporterID: 4, ReportedID: 9, Reason: models.ReportOther, Status: models.ReportDismissed})
+
+    reports, err := suite.repo.FindByStatus(models.ReportOpen, 0, 0)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), reports, 3)
+    assert.Equal(suite.T(), uint(1), reports[0].ReporterID)
+    assert.Equal(suite.T(), uint(3), reports[2].ReporterID)
+
+    page, err := suite.repo.FindByStatus(models.ReportOpen, 2, 2)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), page, 1)
+    assert.Equal(suite.T(), uint(3), page[0].ReporterID)
+}
+
+func (suite *ReportRepositoryTestSuite) TestReview() {
+    report := &models.Report{ReporterID: 1, ReportedID: 2, Reason: models.ReportSpam}
+    suite.repo.Create(report)
+
+    reviewed, err := suite.repo.Review(report.ID, 7, models.ReportResolved)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), models.ReportResolved, reviewed.Status)
+    assert.Equal(suite.T(), uint(7), *reviewed.ReviewerID)
+    assert.NotNil(suite.T(), reviewed.ReviewedAt)
+
+    // A closed report can't be reviewed again
+    _, err = suite.repo.Review(report.ID, 8, models.ReportDismissed)
+    assert.ErrorIs(suite.T(), err, gorm.ErrRecordNotFound)
+
+    _, err = suite.repo.Review(999, 7, models.ReportResolved)
+    assert.ErrorIs(suite.T(), err, gorm.ErrRecordNotFound)
+}
+
+func (suite *ReportRepositoryTestSuite) TestReportReasonValid() {
+    assert.True(suite.T(), models.ReportFakeProfile.Valid())
+    assert.False(suite.T(), models.ReportReason("rude").Valid())
+}
+
+func TestReportRepositorySuite(t *testing.T) {
+    suite.Run(t, new(ReportRepositoryTestSuite))
+}
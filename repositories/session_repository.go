@@ -0,0 +1,88 @@
+package repositories
+
+import (
+    "time"
+
+    "github.com/connectplus/models"
+    "gorm.io/gorm"
+)
+
+type SessionRepository interface {
+    Create(session *models.Session) error
+    FindByID(id uint) (*models.Session, error)
+    FindByRefreshTokenHash(hash string) (*models.Session, error)
+    FindByPreviousTokenHash(hash string) (*models.Session, error)
+    // Rotate replaces the session's refresh token hash, provided oldHash is
+    // still current and the session is not revoked. It returns
+    // gorm.ErrRecordNotFound if another request rotated it first.
+    Rotate(id uint, oldHash, newHash string, expiresAt time.Time) error
+    Revoke(id uint) error
+    RevokeAllForUser(userID uint) error
+    // RevokeOthersForUser revokes every session of the user except keepID.
+    RevokeOthersForUser(userID, keepID uint) error
+}
+
+type sessionRepository struct {
+    db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+    return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(session *models.Session) error {
+    return r.db.Create(session).Error
+}
+
+func (r *sessionRepository) FindByID(id uint) (*models.Session, error) {
+    var session models.Session
+    err := r.db.First(&session, id).Error
+    return &session, err
+}
+
+func (r *sessionRepository) FindByRefreshTokenHash(hash string) (*models.Session, error) {
+    var session models.Session
+    err := r.db.Where("refresh_token_hash = ?", hash).First(&session).Error
+    return &session, err
+}
+
+func (r *sessionRepository) FindByPreviousTokenHash(hash string) (*models.Session, error) {
+    var session models.Session
+    err := r.db.Where("previous_token_hash = ?", hash).First(&session).Error
+    return &session, err
+}
+
+func (r *sessionRepository) Rotate(id uint, oldHash, newHash string, expiresAt time.Time) error {
+    result := r.db.Model(&models.Session{}).
+        Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", id, oldHash).
+        Updates(map[string]interface{}{
+            "refresh_token_hash":  newHash,
+            "previous_token_hash": oldHash,
+            "expires_at":          expiresAt,
+        })
+    if result.Error != nil {
+        return result.Error
+    }
+    if result.RowsAffected == 0 {
+        return gorm.ErrRecordNotFound
+    }
+    return nil
+}
+
+func (r *sessionRepository) Revoke(id uint) error {
+    return r.db.Model(&models.Session{}).
+        Where("id = ? AND revoked_at IS NULL", id).
+        Update("revoked_at", time.Now()).Error
+}
+
+func (r *sessionRepository) RevokeAllForUser(userID uint) error {
+    return r.db.Model(&models.Session{}).
+        Where("user_id = ? AND revoked_at IS NULL", userID).
+        Update("revoked_at", time.Now()).Error
+}
+
+func (r *sessionRepository) RevokeOthersForUser(userID, keepID uint) error {
+    return r.db.Model(&models.Session{}).
+        Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepID).
+        Update("revoked_at", time.Now()).Error
+}
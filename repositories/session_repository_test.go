@@ -0,0 +1,161 @@
+package repositories
+
+import (
+    "testing"
+    "time"
+    
+    "github.com/connectplus/models"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/suite"
+    "gorm.io/driver/sqlite"
+    "gorm.io/gorm"
+)
+
+type SessionRepositoryTestSuite struct {
+    suite.Suite
+    db *gorm.DB
+    repo SessionRepository
+}
+
+func (suite *SessionRepositoryTestSuite) SetupTest() {
+    var err error
+    suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+    assert.NoError(suite.T(), err)
+    
+    // Migrate the schema for both User and Session
+    err = suite.db.AutoMigrate(&models.User{}, &models.Session{})
+    assert.NoError(suite.T(), err)
+    
+    suite.repo = NewSessionRepository(suite.db)
+}
+
+func (suite *SessionRepositoryTestSuite) TearDownTest() {
+    db, _ := suite.db.DB()
+    db.Close()
+}
+
+func (suite *SessionRepositoryTestSuite) newSession(userID uint, hash string) *models.Session {
+    session := &models.Session{
+        UserID:           userID,
+        RefreshTokenHash: hash,
+        ExpiresAt:        time.Now().Add(time.Hour),
+    }
+    err := suite.repo.Create(session)
+    assert.NoError(suite.T(), err)
+    return session
+}
+
+func (suite *SessionRepositoryTestSuite) TestCreateSession() {
+    session := suite.newSession(1, "hash1")
+    assert.NotZero(suite.T(), session.ID)
+    assert.True(suite.T(), session.IsActive(time.Now()))
+}
+
+func (suite *SessionRepositoryTestSuite) TestCreateDuplicateHash() {
+    suite.newSession(1, "hash1")
+    
+    err := suite.repo.Create(&models.Session{UserID: 2, RefreshTokenHash: "hash1", ExpiresAt: time.Now()})
+    assert.Error(suite.T(), err)
+}
+
+func (suite *SessionRepositoryTestSuite) TestFindByRefreshTokenHash() {
+    session := suite.newSession(1, "hash1")
+    
+    found, err := suite.repo.FindByRefreshTokenHash("hash1")
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), session.ID, found.ID)
+    
+    _, err = suite.repo.FindByRefreshTokenHash("missing")
+    assert.Equal(suite.T(), gorm.ErrRecordNotFound, err)
+}
+
+func (suite *SessionRepositoryTestSuite) TestRotate() {
+    session := suite.newSession(1, "hash1")
+    
+    err := suite.repo.Rotate(session.ID, "hash1", "hash2", time.Now().Add(2*time.Hour))
+    assert.NoError(suite.T(), err)
+    
+    found, err := suite.repo.FindByRefreshTokenHash("hash2")
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), session.ID, found.ID)
+    
+    // The old token is remembered for reuse detection only
+    _, err = suite.repo.FindByRefreshTokenHash("hash1")
+    assert.Equal(suite.T(), gorm.ErrRecordNotFound, err)
+    previous, err := suite.repo.FindByPreviousTokenHash("hash1")
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), session.ID, previous.ID)
+}
+
+func (suite *SessionRepositoryTestSuite) TestRotateStaleHash() {
+    session := suite.newSession(1, "hash1")
+    assert.NoError(suite.T(), suite.repo.Rotate(session.ID, "hash1", "hash2", time.Now().Add(time.Hour)))
+    
+    // A second rotation with the same old hash loses the race
+    err := suite.repo.Rotate(session.ID, "hash1", "hash3", time.Now().Add(time.Hour))
+    assert.Equal(suite.T(), gorm.ErrRecordNotFound, err)
+}
+
+func (suite *SessionRepositoryTestSuite) TestRotateRevokedSession() {
+    session := suite.newSession(1, "hash1")
+    assert.NoError(suite.T(), suite.repo.Revoke(session.ID))
+    
+    err := suite.repo.Rotate(session.ID, "hash1", "hash2", time.Now().Add(time.Hour))
+    assert.Equal(suite.T(), gorm.ErrRecordNotFound, err)
+}
+
+func (suite *SessionRepositoryTestSuite) TestRevoke() {
+    session := suite.newSession(1, "hash1")
+    
+    err := suite.repo.Revoke(session.ID)
+    assert.NoError(suite.T(), err)
+    
+    found, err := suite.repo.FindByID(session.ID)
+    assert.NoError(suite.T(), err)
+    assert.NotNil(suite.T(), found.RevokedAt)
+    assert.False(suite.T(), found.IsActive(time.Now()))
+}
+
+func (suite *SessionRepositoryTestSuite) TestRevokeAllForUser() {
+    first := suite.newSession(1, "hash1")
+    second := suite.newSession(1, "hash2")
+    other := suite.newSession(2, "hash3")
+    
+    err := suite.repo.RevokeAllForUser(1)
+    assert.NoError(suite.T(), err)
+    
+    for _, id := range []uint{first.ID, second.ID} {
+        found, err := suite.repo.FindByID(id)
+        assert.NoError(suite.T(), err)
+        assert.False(suite.T(), found.IsActive(time.Now()))
+    }
+    
+    found, err := suite.repo.FindByID(other.ID)
+    assert.NoError(suite.T(), err)
+    assert.True(suite.T(), found.IsActive(time.Now()))
+}
+
+func (suite *SessionRepositoryTestSuite) TestRevokeOthersForUser() {
+    current := suite.newSession(1, "hash1")
+    other := suite.newSession(1, "hash2")
+    
+    err := suite.repo.RevokeOthersForUser(1, current.ID)
+    assert.NoError(suite.T(), err)
+    
+    found, err := suite.repo.FindByID(current.ID)
+    assert.NoError(suite.T(), err)
+    assert.True(suite.T(), found.IsActive(time.Now()))
+    
+    found, err = suite.repo.FindByID(other.ID)
+    assert.NoError(suite.T(), err)
+    assert.False(suite.T(), found.IsActive(time.Now()))
+}
+
+func (suite *SessionRepositoryTestSuite) TestExpiredSessionIsInactive() {
+    session := &models.Session{ExpiresAt: time.Now().Add(-time.Minute)}
+    assert.False(suite.T(), session.IsActive(time.Now()))
+}
+
+func TestSessionRepositorySuite(t *testing.T) {
+    suite.Run(t, new(SessionRepositoryTestSuite))
+}
@@ -0,0 +1,135 @@
+package repositories
+
+import (
+    "errors"
+
+    "github.com/connectplus/models"
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+)
+
+type SwipeRepository interface {
+    // Create records a swipe, replacing any earlier swipe on the same user.
+    // If the swipe is a like and the other user has already liked back, a
+    // pending match for the other user to accept is created and its
+    // creation recorded in the same transaction and returned; otherwise
+    // the returned match is nil. A previously unmatched pair's match is
+    // made pending again instead. Callers enforce the re-match cooldown.
+    Create(swipe *models.Swipe) (*models.Match, error)
+    FindBySwiperID(swiperID uint) ([]models.Swipe, error)
+    FindBySwiperAndSwiped(swiperID, swipedID uint) (*models.Swipe, error)
+}
+
+type swipeRepository struct {
+    db *gorm.DB
+}
+
+func NewSwipeRepository(db *gorm.DB) SwipeRepository {
+    return &swipeRepository{db: db}
+}
+
+func (r *swipeRepository) Create(swipe *models.Swipe) (*models.Match, error) {
+    var match *models.Match
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        // Lock both users in a fixed order so two concurrent likes between
+        // the same pair serialize and exactly one of them sees the other.
+        low, high := orderedPair(swipe.SwiperID, swipe.SwipedID)
+        var ids []uint
+        if err := tx.Model(&models.User{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+            Where("id IN ?", []uint{low, high}).Order("id").Pluck("id", &ids).Error; err != nil {
+            return err
+        }
+
+        if err := tx.Clauses(clause.OnConflict{
+            Columns:   []clause.Column{{Name: "swiper_id"}, {Name: "swiped_id"}},
+            DoUpdates: clause.AssignmentColumns([]string{"direction", "updated_at"}),
+        }).Create(swipe).Error; err != nil {
+            return err
+        }
+
+        if swipe.Direction != models.SwipeLike {
+            return nil
+        }
+
+        var reciprocal models.Swipe
+        err := tx.Where("swiper_id = ? AND swiped_id = ? AND direction = ?",
+            swipe.SwipedID, swipe.SwiperID, models.SwipeLike).First(&reciprocal).Error
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return nil
+        }
+        if err != nil {
+            return err
+        }
+
+        matches := NewMatchRepository(tx)
+        existing, err := matches.FindByUsers(low, high)
+        if err == nil {
+            if existing.Status != models.MatchUnmatched {
+                return nil
+            }
+            // A pair who unmatched liked each other again; the old match
+            // row is reused since a pair can only have one
+            from := existing.Status
+            if err := tx.Model(existing).Updates(map[string]interface{}{
+                "status":       models.MatchPending,
+                "initiator_id": swipe.SwiperID,
+                "accepted_at":  nil,
+                "extended_at":  nil,
+                "unmatched_at": nil,
+            }).Error; err != nil {
+                return err
+            }
+            existing.Status = models.MatchPending
+            existing.InitiatorID = &swipe.SwiperID
+            existing.AcceptedAt = nil
+            existing.ExtendedAt = nil
+            existing.UnmatchedAt = nil
+            match = existing
+            return tx.Create(&models.MatchEvent{
+                MatchID:    match.ID,
+                ActorID:    &swipe.SwiperID,
+                FromStatus: from,
+                ToStatus:   models.MatchPending,
+            }).Error
+        }
+        if !errors.Is(err, gorm.ErrRecordNotFound) {
+            return err
+        }
+
+        // The match waits for the other user, who liked first, to accept it
+        match = &models.Match{User1ID: low, User2ID: high, Status: models.MatchPending, InitiatorID: &swipe.SwiperID}
+        if err := matches.Create(match); err != nil {
+            return err
+        }
+        return tx.Create(&models.MatchEvent{
+            MatchID:  match.ID,
+            ActorID:  &swipe.SwiperID,
+            ToStatus: models.MatchPending,
+        }).Error
+    })
+    if err != nil {
+        return nil, err
+    }
+    return match, nil
+}
+
+func (r *swipeRepository) FindBySwiperID(swiperID uint) ([]models.Swipe, error) {
+    var swipes []models.Swipe
+    err := r.db.Where("swiper_id = ?", swiperID).Find(&swipes).Error
+    return swipes, err
+}
+
+func (r *swipeRepository) FindBySwiperAndSwiped(swiperID, swipedID uint) (*models.Swipe, error) {
+    var swipe models.Swipe
+    err := r.db.Where("swiper_id = ? AND swiped_id = ?", swiperID, swipedID).First(&swipe).Error
+    return &swipe, err
+}
+
+// orderedPair returns the two user IDs lowest first, which is the order
+// matches created from swipes are stored in.
+func orderedPair(a, b uint) (uint, uint) {
+    if a < b {
+        return a, b
+    }
+    return b, a
+}
@@ -0,0 +1,145 @@
+package repositories
+
+import (
+    "testing"
+    "time"
+    
+    "github.com/connectplus/models"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/suite"
+    "gorm.io/driver/sqlite"
+    "gorm.io/gorm"
+)
+
+type SwipeRepositoryTestSuite struct {
+    suite.Suite
+    db *gorm.DB
+    repo SwipeRepository
+}
+
+func (suite *SwipeRepositoryTestSuite) SetupTest() {
+    var err error
+    suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+    assert.NoError(suite.T(), err)
+    
+    // Migrate the schema for User, Swipe and Match
+    err = suite.db.AutoMigrate(&models.User{}, &models.Swipe{}, &models.Match{}, &models.MatchEvent{})
+    assert.NoError(suite.T(), err)
+    
+    suite.repo = NewSwipeRepository(suite.db)
+}
+
+func (suite *SwipeRepositoryTestSuite) TearDownTest() {
+    db, _ := suite.db.DB()
+    db.Close()
+}
+
+func (suite *SwipeRepositoryTestSuite) TestCreateSwipe() {
+    swipe := &models.Swipe{SwiperID: 1, SwipedID: 2, Direction: models.SwipeLike}
+    
+    match, err := suite.repo.Create(swipe)
+    assert.NoError(suite.T(), err)
+    assert.NotZero(suite.T(), swipe.ID)
+    assert.Nil(suite.T(), match)
+}
+
+func (suite *SwipeRepositoryTestSuite) TestMutualLikeCreatesMatch() {
+    _, err := suite.repo.Create(&models.Swipe{SwiperID: 2, SwipedID: 1, Direction: models.SwipeLike})
+    assert.NoError(suite.T(), err)
+    
+    match, err := suite.repo.Create(&models.Swipe{SwiperID: 1, SwipedID: 2, Direction: models.SwipeLike})
+    assert.NoError(suite.T(), err)
+    assert.NotNil(suite.T(), match)
+    assert.NotZero(suite.T(), match.ID)
+    assert.Equal(suite.T(), uint(1), match.User1ID)
+    assert.Equal(suite.T(), uint(2), match.User2ID)
+    assert.Equal(suite.T(), models.MatchPending, match.Status)
+    assert.Nil(suite.T(), match.AcceptedAt)
+    // User 2 liked first, so they are the one to accept
+    assert.Equal(suite.T(), uint(1), *match.InitiatorID)
+    assert.True(suite.T(), match.CanRespond(2))
+    assert.False(suite.T(), match.CanRespond(1))
+    
+    // Verify the match was persisted
+    var count int64
+    suite.db.Model(&models.Match{}).Count(&count)
+    assert.Equal(suite.T(), int64(1), count)
+}
+
+func (suite *SwipeRepositoryTestSuite) TestPassDoesNotCreateMatch() {
+    _, err := suite.repo.Create(&models.Swipe{SwiperID: 2, SwipedID: 1, Direction: models.SwipeLike})
+    assert.NoError(suite.T(), err)
+    
+    match, err := suite.repo.Create(&models.Swipe{SwiperID: 1, SwipedID: 2, Direction: models.SwipePass})
+    assert.NoError(suite.T(), err)
+    assert.Nil(suite.T(), match)
+    
+    var count int64
+    suite.db.Model(&models.Match{}).Count(&count)
+    assert.Equal(suite.T(), int64(0), count)
+}
+
+func (suite *SwipeRepositoryTestSuite) TestReswipeOverwritesDirection() {
+    _, err := suite.repo.Create(&models.Swipe{SwiperID: 1, SwipedID: 2, Direction: models.SwipePass})
+    assert.NoError(suite.T(), err)
+    
+    _, err = suite.repo.Create(&models.Swipe{SwiperID: 1, SwipedID: 2, Direction: models.SwipeLike})
+    assert.NoError(suite.T(), err)
+    
+    swipe, err := suite.repo.FindBySwiperAndSwiped(1, 2)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), models.SwipeLike, swipe.Direction)
+    
+    swipes, err := suite.repo.FindBySwiperID(1)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), swipes, 1)
+}
+
+func (suite *SwipeRepositoryTestSuite) TestRepeatedLikeDoesNotDuplicateMatch() {
+    _, err := suite.repo.Create(&models.Swipe{SwiperID: 1, SwipedID: 2, Direction: models.SwipeLike})
+    assert.NoError(suite.T(), err)
+    match, err := suite.repo.Create(&models.Swipe{SwiperID: 2, SwipedID: 1, Direction: models.SwipeLike})
+    assert.NoError(suite.T(), err)
+    assert.NotNil(suite.T(), match)
+    
+    // Liking again after the match exists must not create a second one
+    match, err = suite.repo.Create(&models.Swipe{SwiperID: 1, SwipedID: 2, Direction: models.SwipeLike})
+    assert.NoError(suite.T(), err)
+    assert.Nil(suite.T(), match)
+    
+    var count int64
+    suite.db.Model(&models.Match{}).Count(&count)
+    assert.Equal(suite.T(), int64(1), count)
+}
+
+func (suite *SwipeRepositoryTestSuite) TestMutualLikeReactivatesUnmatchedPair() {
+    unmatchedAt := time.Now().Add(-48 * time.Hour)
+    old := &models.Match{User1ID: 1, User2ID: 2, Status: models.MatchUnmatched, UnmatchedAt: &unmatchedAt}
+    suite.db.Create(old)
+
+    _, err := suite.repo.Create(&models.Swipe{SwiperID: 2, SwipedID: 1, Direction: models.SwipeLike})
+    assert.NoError(suite.T(), err)
+    match, err := suite.repo.Create(&models.Swipe{SwiperID: 1, SwipedID: 2, Direction: models.SwipeLike})
+    assert.NoError(suite.T(), err)
+    assert.NotNil(suite.T(), match)
+    assert.Equal(suite.T(), old.ID, match.ID)
+    assert.Equal(suite.T(), models.MatchPending, match.Status)
+    assert.Equal(suite.T(), uint(1), *match.InitiatorID)
+    assert.Nil(suite.T(), match.UnmatchedAt)
+
+    var events []models.MatchEvent
+    suite.db.Where("match_id = ?", old.ID).Find(&events)
+    assert.Len(suite.T(), events, 1)
+    assert.Equal(suite.T(), models.MatchUnmatched, events[0].FromStatus)
+    assert.Equal(suite.T(), models.MatchPending, events[0].ToStatus)
+}
+
+func (suite *SwipeRepositoryTestSuite) TestFindNonExistentSwipe() {
+    _, err := suite.repo.FindBySwiperAndSwiped(1, 999)
+    assert.Error(suite.T(), err)
+    assert.Equal(suite.T(), gorm.ErrRecordNotFound, err)
+}
+
+func TestSwipeRepositorySuite(t *testing.T) {
+    suite.Run(t, new(SwipeRepositoryTestSuite))
+}
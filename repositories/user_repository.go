@@ -1,15 +1,56 @@
 package repositories
 
 import (
+    "errors"
+    "fmt"
+    "time"
+
     "github.com/connectplus/models"
     "gorm.io/gorm"
 )
 
+// ErrNotSuspended is returned when reactivating a user whose account is
+// inactive for some reason other than a suspension.
+var ErrNotSuspended = errors.New("user is not suspended")
+
+// UserRepository finds users who haven't been deleted; see Delete.
 type UserRepository interface {
     Create(user *models.User) error
     FindByID(id uint) (*models.User, error)
+    // FindByIDs returns those of the users that exist, in no particular
+    // order.
+    FindByIDs(ids []uint) ([]models.User, error)
     FindByEmail(email string) (*models.User, error)
     Update(user *models.User) error
+    // SetActive suspends or reactivates a user. Suspending means the
+    // account no longer counts as deactivated by the user, so a suspended
+    // user can't reactivate themselves by logging in, and a deletion the
+    // user asked for still goes ahead. Reactivating only lifts a
+    // suspension: it returns ErrNotSuspended for accounts their owner
+    // deactivated or that are due for deletion, and leaves them as they are.
+    SetActive(id uint, active bool) error
+    SetRole(id uint, role models.Role) error
+    SetLastLogin(id uint, at time.Time) error
+    // SetLastActive records when each user was last active, never moving a
+    // user's time backwards.
+    SetLastActive(lastActive map[uint]time.Time) error
+    // Deactivate marks the account deactivated by its owner, to be purged at
+    // deleteAt if that is set.
+    Deactivate(id uint, deleteAt *time.Time) error
+    // Reactivate undoes Deactivate, cancelling any scheduled deletion. It
+    // returns gorm.ErrRecordNotFound unless the user deactivated their own
+    // account.
+    Reactivate(id uint) error
+    // FindDueForDeletion returns users whose scheduled deletion is at or
+    // before now.
+    FindDueForDeletion(now time.Time) ([]models.User, error)
+    // Delete removes the user's profile, preferences, swipes, blocks,
+    // matches, messages, sessions, tokens, data exports, notifications and
+    // devices, and scrubs the user row itself, which is kept as a purged
+    // placeholder. What moderators may still need is retained: every report
+    // filed by or against the user, the audit log, and the messages
+    // exchanged with anyone they share an open report with, hidden from
+    // users. Stored photos and export archives are the caller's to remove.
     Delete(id uint) error
 }
 
@@ -27,13 +68,22 @@ func (r *userRepository) Create(user *models.User) error {
 
 func (r *userRepository) FindByID(id uint) (*models.User, error) {
     var user models.User
-    err := r.db.First(&user, id).Error
+    err := r.db.Where("purged_at IS NULL").First(&user, id).Error
     return &user, err
 }
 
+func (r *userRepository) FindByIDs(ids []uint) ([]models.User, error) {
+    var users []models.User
+    if len(ids) == 0 {
+        return users, nil
+    }
+    err := r.db.Where("id IN ? AND purged_at IS NULL", ids).Find(&users).Error
+    return users, err
+}
+
 func (r *userRepository) FindByEmail(email string) (*models.User, error) {
     var user models.User
-    err := r.db.Where("email = ?", email).First(&user).Error
+    err := r.db.Where("email = ? AND purged_at IS NULL", email).First(&user).Error
     return &user, err
 }
 
@@ -41,6 +91,169 @@ func (r *userRepository) Update(user *models.User) error {
     return r.db.Save(user).Error
 }
 
+func (r *userRepository) SetActive(id uint, active bool) error {
+    if !active {
+        return r.updateColumns(id, map[string]interface{}{"is_active": false, "deactivated_at": nil})
+    }
+
+    result := r.db.Model(&models.User{}).
+        Where("id = ? AND is_active = ?", id, false).
+        Where("deactivated_at IS NULL AND deletion_scheduled_at IS NULL AND purged_at IS NULL").
+        Update("is_active", true)
+    if result.Error != nil {
+        return result.Error
+    }
+    if result.RowsAffected > 0 {
+        return nil
+    }
+
+    // Nothing to lift; tell an active account from one inactive for
+    // another reason
+    user, err := r.FindByID(id)
+    if err != nil {
+        return err
+    }
+    if !user.IsActive {
+        return ErrNotSuspended
+    }
+    return nil
+}
+
+func (r *userRepository) SetRole(id uint, role models.Role) error {
+    return r.updateColumns(id, map[string]interface{}{"role": role})
+}
+
+func (r *userRepository) SetLastLogin(id uint, at time.Time) error {
+    return r.updateColumns(id, map[string]interface{}{"last_login_at": at})
+}
+
+func (r *userRepository) SetLastActive(lastActive map[uint]time.Time) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        for id, at := range lastActive {
+            err := tx.Model(&models.User{}).
+                Where("id = ? AND (last_active_at IS NULL OR last_active_at < ?)", id, at).
+                Update("last_active_at", at).Error
+            if err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+func (r *userRepository) Deactivate(id uint, deleteAt *time.Time) error {
+    return r.updateColumns(id, map[string]interface{}{
+        "is_active":             false,
+        "deactivated_at":        time.Now(),
+        "deletion_scheduled_at": deleteAt,
+    })
+}
+
+func (r *userRepository) Reactivate(id uint) error {
+    result := r.db.Model(&models.User{}).Where("id = ? AND deactivated_at IS NOT NULL", id).
+        Updates(map[string]interface{}{
+            "is_active":             true,
+            "deactivated_at":        nil,
+            "deletion_scheduled_at": nil,
+        })
+    if result.Error != nil {
+        return result.Error
+    }
+    if result.RowsAffected == 0 {
+        return gorm.ErrRecordNotFound
+    }
+    return nil
+}
+
+func (r *userRepository) FindDueForDeletion(now time.Time) ([]models.User, error) {
+    var users []models.User
+    err := r.db.Where("deletion_scheduled_at <= ?", now).Order("deletion_scheduled_at asc").Find(&users).Error
+    return users, err
+}
+
+// updateColumns sets columns of a user, returning gorm.ErrRecordNotFound if
+// the user doesn't exist
+func (r *userRepository) updateColumns(id uint, columns map[string]interface{}) error {
+    result := r.db.Model(&models.User{}).Where("id = ?", id).Updates(columns)
+    if result.Error != nil {
+        return result.Error
+    }
+    if result.RowsAffected == 0 {
+        return gorm.ErrRecordNotFound
+    }
+    return nil
+}
+
 func (r *userRepository) Delete(id uint) error {
-    return r.db.Delete(&models.User{}, id).Error
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        var user models.User
+        if err := tx.Where("purged_at IS NULL").First(&user, id).Error; err != nil {
+            return err
+        }
+
+        if err := NewProfileRepository(tx).Delete(id); err != nil {
+            return err
+        }
+        if err := NewPreferenceRepository(tx).Delete(id); err != nil {
+            return err
+        }
+
+        matchIDs := tx.Model(&models.Match{}).Select("id").Where("user1_id = ? OR user2_id = ?", id, id)
+        if err := tx.Where("match_id IN (?)", matchIDs).Delete(&models.MatchEvent{}).Error; err != nil {
+            return err
+        }
+
+        // Messages with anyone in an open report with the user are evidence
+        // for moderators; keep them, out of sight of the other user
+        reportedWith := tx.Model(&models.Report{}).
+            Select("CASE WHEN reporter_id = ? THEN reported_id ELSE reporter_id END", id).
+            Where("(reporter_id = ? OR reported_id = ?) AND status = ?", id, id, models.ReportOpen)
+        err := tx.Model(&models.Message{}).
+            Where("(sender_id = ? AND receiver_id IN (?)) OR (receiver_id = ? AND sender_id IN (?))",
+                id, reportedWith, id, reportedWith).
+            Where("hidden_at IS NULL").
+            Update("hidden_at", time.Now()).Error
+        if err != nil {
+            return err
+        }
+        err = tx.Where("sender_id = ? OR receiver_id = ?", id, id).
+            Where("sender_id NOT IN (?) AND receiver_id NOT IN (?)", reportedWith, reportedWith).
+            Delete(&models.Message{}).Error
+        if err != nil {
+            return err
+        }
+
+        // Rows that belong to the user on either side
+        for _, owned := range []struct {
+            model interface{}
+            where string
+        }{
+            {&models.Match{}, "user1_id = ? OR user2_id = ?"},
+            {&models.Swipe{}, "swiper_id = ? OR swiped_id = ?"},
+            {&models.Block{}, "blocker_id = ? OR blocked_id = ?"},
+        } {
+            if err := tx.Where(owned.where, id, id).Delete(owned.model).Error; err != nil {
+                return err
+            }
+        }
+        for _, owned := range []interface{}{&models.Session{}, &models.UserToken{}, &models.DataExport{}, &models.Notification{}, &models.Device{}} {
+            if err := tx.Where("user_id = ?", id).Delete(owned).Error; err != nil {
+                return err
+            }
+        }
+
+        // Reports, audit entries and the messages kept above still refer to
+        // the user, and the schema would cascade deleting it to them
+        return tx.Model(&user).Updates(map[string]interface{}{
+            "email":                 fmt.Sprintf("purged-%d@purged.invalid", id),
+            "password_hash":         "",
+            "is_active":             false,
+            "is_verified":           false,
+            "role":                  models.RoleUser,
+            "last_active_at":        nil,
+            "deactivated_at":        nil,
+            "deletion_scheduled_at": nil,
+            "purged_at":             time.Now(),
+        }).Error
+    })
 }
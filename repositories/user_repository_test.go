@@ -2,6 +2,7 @@ package repositories
 
 import (
     "testing"
+    "time"
     
     "github.com/connectplus/models"
     "github.com/stretchr/testify/assert"
@@ -21,8 +22,10 @@ func (suite *UserRepositoryTestSuite) SetupTest() {
     suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
     assert.NoError(suite.T(), err)
     
-    // Migrate the schema
-    err = suite.db.AutoMigrate(&models.User{})
+    // Migrate the schema, including everything Delete cascades to
+    err = suite.db.AutoMigrate(&models.User{}, &models.Profile{}, &models.Preference{}, &models.Match{},
+        &models.MatchEvent{}, &models.Message{}, &models.Swipe{}, &models.Block{}, &models.Report{},
+        &models.Session{}, &models.UserToken{}, &models.DataExport{}, &models.Notification{}, &models.Device{}, &models.AuditLog{})
     assert.NoError(suite.T(), err)
     
     suite.repo = NewUserRepository(suite.db)
@@ -59,6 +62,22 @@ func (suite *UserRepositoryTestSuite) TestFindByID() {
     assert.Equal(suite.T(), user.Email, foundUser.Email)
 }
 
+func (suite *UserRepositoryTestSuite) TestFindByIDs() {
+    one := &models.User{Email: "one@example.com", PasswordHash: "testpass"}
+    two := &models.User{Email: "two@example.com", PasswordHash: "testpass"}
+    suite.db.Create(one)
+    suite.db.Create(two)
+
+    users, err := suite.repo.FindByIDs([]uint{one.ID, 999})
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), users, 1)
+    assert.Equal(suite.T(), "one@example.com", users[0].Email)
+
+    users, err = suite.repo.FindByIDs(nil)
+    assert.NoError(suite.T(), err)
+    assert.Empty(suite.T(), users)
+}
+
 func (suite *UserRepositoryTestSuite) TestFindByEmail() {
     // Create test user
     user := &models.User{
@@ -126,6 +145,246 @@ func (suite *UserRepositoryTestSuite) TestUpdateUser() {
     assert.Equal(suite.T(), "updated@example.com", updatedUser.Email)
 }
 
+func (suite *UserRepositoryTestSuite) TestNewUserHasUserRole() {
+    user := &models.User{Email: "test@example.com", PasswordHash: "testpass"}
+    suite.db.Create(user)
+
+    foundUser, err := suite.repo.FindByID(user.ID)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), models.RoleUser, foundUser.Role)
+}
+
+func (suite *UserRepositoryTestSuite) TestSetActive() {
+    user := &models.User{Email: "test@example.com", PasswordHash: "testpass", IsActive: true}
+    suite.db.Create(user)
+
+    err := suite.repo.SetActive(user.ID, false)
+    assert.NoError(suite.T(), err)
+    foundUser, _ := suite.repo.FindByID(user.ID)
+    assert.False(suite.T(), foundUser.IsActive)
+
+    err = suite.repo.SetActive(user.ID, true)
+    assert.NoError(suite.T(), err)
+    foundUser, _ = suite.repo.FindByID(user.ID)
+    assert.True(suite.T(), foundUser.IsActive)
+
+    err = suite.repo.SetActive(999, false)
+    assert.ErrorIs(suite.T(), err, gorm.ErrRecordNotFound)
+}
+
+func (suite *UserRepositoryTestSuite) TestSetRole() {
+    user := &models.User{Email: "test@example.com", PasswordHash: "testpass"}
+    suite.db.Create(user)
+
+    err := suite.repo.SetRole(user.ID, models.RoleModerator)
+    assert.NoError(suite.T(), err)
+    foundUser, _ := suite.repo.FindByID(user.ID)
+    assert.Equal(suite.T(), models.RoleModerator, foundUser.Role)
+
+    err = suite.repo.SetRole(999, models.RoleAdmin)
+    assert.ErrorIs(suite.T(), err, gorm.ErrRecordNotFound)
+}
+
+func (suite *UserRepositoryTestSuite) TestSetLastLogin() {
+    user := &models.User{Email: "test@example.com", PasswordHash: "testpass"}
+    suite.db.Create(user)
+    at := time.Now().Truncate(time.Second)
+
+    assert.NoError(suite.T(), suite.repo.SetLastLogin(user.ID, at))
+    foundUser, _ := suite.repo.FindByID(user.ID)
+    assert.True(suite.T(), at.Equal(foundUser.LastLoginAt))
+
+    err := suite.repo.SetLastLogin(999, at)
+    assert.ErrorIs(suite.T(), err, gorm.ErrRecordNotFound)
+}
+
+func (suite *UserRepositoryTestSuite) TestSetLastActive() {
+    one := &models.User{Email: "one@example.com", PasswordHash: "testpass"}
+    two := &models.User{Email: "two@example.com", PasswordHash: "testpass"}
+    suite.db.Create(one)
+    suite.db.Create(two)
+    earlier := time.Now().Add(-time.Hour).Truncate(time.Second)
+    later := earlier.Add(30 * time.Minute)
+
+    err := suite.repo.SetLastActive(map[uint]time.Time{one.ID: later, two.ID: earlier, 999: later})
+    assert.NoError(suite.T(), err)
+
+    // A stale time from another instance doesn't move it backwards
+    err = suite.repo.SetLastActive(map[uint]time.Time{one.ID: earlier})
+    assert.NoError(suite.T(), err)
+
+    foundOne, _ := suite.repo.FindByID(one.ID)
+    foundTwo, _ := suite.repo.FindByID(two.ID)
+    assert.True(suite.T(), later.Equal(*foundOne.LastActiveAt))
+    assert.True(suite.T(), earlier.Equal(*foundTwo.LastActiveAt))
+}
+
+func (suite *UserRepositoryTestSuite) TestRoleAtLeast() {
+    assert.True(suite.T(), models.RoleAdmin.AtLeast(models.RoleModerator))
+    assert.True(suite.T(), models.RoleModerator.AtLeast(models.RoleModerator))
+    assert.False(suite.T(), models.RoleUser.AtLeast(models.RoleModerator))
+    assert.False(suite.T(), models.Role("").AtLeast(models.RoleUser))
+}
+
+func (suite *UserRepositoryTestSuite) TestDeactivateAndReactivate() {
+    user := &models.User{Email: "test@example.com", PasswordHash: "testpass"}
+    suite.db.Create(user)
+
+    deleteAt := time.Now().Add(24 * time.Hour)
+    err := suite.repo.Deactivate(user.ID, &deleteAt)
+    assert.NoError(suite.T(), err)
+    foundUser, _ := suite.repo.FindByID(user.ID)
+    assert.False(suite.T(), foundUser.IsActive)
+    assert.NotNil(suite.T(), foundUser.DeactivatedAt)
+    assert.NotNil(suite.T(), foundUser.DeletionScheduledAt)
+
+    err = suite.repo.Reactivate(user.ID)
+    assert.NoError(suite.T(), err)
+    foundUser, _ = suite.repo.FindByID(user.ID)
+    assert.True(suite.T(), foundUser.IsActive)
+    assert.Nil(suite.T(), foundUser.DeactivatedAt)
+    assert.Nil(suite.T(), foundUser.DeletionScheduledAt)
+}
+
+func (suite *UserRepositoryTestSuite) TestSuspendedUserCannotReactivate() {
+    user := &models.User{Email: "test@example.com", PasswordHash: "testpass"}
+    suite.db.Create(user)
+    suite.repo.Deactivate(user.ID, nil)
+
+    // Suspending a deactivated account takes away the owner's way back in
+    err := suite.repo.SetActive(user.ID, false)
+    assert.NoError(suite.T(), err)
+
+    err = suite.repo.Reactivate(user.ID)
+    assert.ErrorIs(suite.T(), err, gorm.ErrRecordNotFound)
+    foundUser, _ := suite.repo.FindByID(user.ID)
+    assert.False(suite.T(), foundUser.IsActive)
+}
+
+func (suite *UserRepositoryTestSuite) TestReactivateOnlyLiftsSuspensions() {
+    deactivated := &models.User{Email: "deactivated@example.com", PasswordHash: "testpass"}
+    pending := &models.User{Email: "pending@example.com", PasswordHash: "testpass"}
+    suite.db.Create(deactivated)
+    suite.db.Create(pending)
+    deleteAt := time.Now().Add(24 * time.Hour)
+    suite.repo.Deactivate(deactivated.ID, nil)
+    suite.repo.Deactivate(pending.ID, &deleteAt)
+
+    err := suite.repo.SetActive(deactivated.ID, true)
+    assert.ErrorIs(suite.T(), err, ErrNotSuspended)
+    foundUser, _ := suite.repo.FindByID(deactivated.ID)
+    assert.False(suite.T(), foundUser.IsActive)
+    assert.NotNil(suite.T(), foundUser.DeactivatedAt)
+
+    // Suspending keeps the scheduled deletion, and reactivating can't
+    // cancel it
+    assert.NoError(suite.T(), suite.repo.SetActive(pending.ID, false))
+    err = suite.repo.SetActive(pending.ID, true)
+    assert.ErrorIs(suite.T(), err, ErrNotSuspended)
+    foundUser, _ = suite.repo.FindByID(pending.ID)
+    assert.False(suite.T(), foundUser.IsActive)
+    assert.NotNil(suite.T(), foundUser.DeletionScheduledAt)
+}
+
+func (suite *UserRepositoryTestSuite) TestFindDueForDeletion() {
+    now := time.Now()
+    past := now.Add(-time.Hour)
+    future := now.Add(time.Hour)
+    due := &models.User{Email: "due@example.com", PasswordHash: "testpass"}
+    later := &models.User{Email: "later@example.com", PasswordHash: "testpass"}
+    kept := &models.User{Email: "kept@example.com", PasswordHash: "testpass"}
+    suite.db.Create(due)
+    suite.db.Create(later)
+    suite.db.Create(kept)
+    suite.repo.Deactivate(due.ID, &past)
+    suite.repo.Deactivate(later.ID, &future)
+
+    users, err := suite.repo.FindDueForDeletion(now)
+    assert.NoError(suite.T(), err)
+    assert.Len(suite.T(), users, 1)
+    assert.Equal(suite.T(), due.ID, users[0].ID)
+}
+
+func (suite *UserRepositoryTestSuite) TestDeleteUserCascades() {
+    user := &models.User{Email: "test@example.com", PasswordHash: "testpass"}
+    other := &models.User{Email: "other@example.com", PasswordHash: "testpass"}
+    suite.db.Create(user)
+    suite.db.Create(other)
+
+    match := &models.Match{User1ID: other.ID, User2ID: user.ID, Status: models.MatchAccepted}
+    suite.db.Create(match)
+    suite.db.Create(&models.MatchEvent{MatchID: match.ID, ToStatus: models.MatchAccepted})
+    suite.db.Create(&models.Profile{UserID: user.ID, DisplayName: "User"})
+    suite.db.Create(&models.Preference{UserID: user.ID})
+    suite.db.Create(&models.Message{SenderID: other.ID, ReceiverID: user.ID, Content: "Hi"})
+    suite.db.Create(&models.Swipe{SwiperID: user.ID, SwipedID: other.ID, Direction: models.SwipeLike})
+    suite.db.Create(&models.Block{BlockerID: other.ID, BlockedID: user.ID})
+    suite.db.Create(&models.Session{UserID: user.ID, RefreshTokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)})
+    suite.db.Create(&models.DataExport{UserID: user.ID})
+    suite.db.Create(&models.Notification{UserID: user.ID, Kind: models.NotificationNewMatch, Title: "It's a match!"})
+    suite.db.Create(&models.Device{UserID: user.ID, Platform: models.DeviceIOS, Token: "token", LastSeenAt: time.Now()})
+    // Unrelated rows must survive
+    suite.db.Create(&models.Profile{UserID: other.ID, DisplayName: "Other"})
+
+    err := suite.repo.Delete(user.ID)
+    assert.NoError(suite.T(), err)
+
+    for _, model := range []interface{}{&models.Profile{}, &models.Preference{}, &models.Match{},
+        &models.MatchEvent{}, &models.Message{}, &models.Swipe{}, &models.Block{}, &models.Session{}, &models.DataExport{}, &models.Notification{}, &models.Device{}} {
+        var count int64
+        suite.db.Model(model).Count(&count)
+        expected := int64(0)
+        if _, ok := model.(*models.Profile); ok {
+            expected = 1
+        }
+        assert.Equal(suite.T(), expected, count, "%T", model)
+    }
+
+    // The user row stays behind, scrubbed, but can't be found
+    _, err = suite.repo.FindByID(user.ID)
+    assert.ErrorIs(suite.T(), err, gorm.ErrRecordNotFound)
+    var purged models.User
+    suite.db.First(&purged, user.ID)
+    assert.NotNil(suite.T(), purged.PurgedAt)
+    assert.False(suite.T(), purged.IsActive)
+    assert.NotEqual(suite.T(), "test@example.com", purged.Email)
+    assert.Empty(suite.T(), purged.PasswordHash)
+    assert.ErrorIs(suite.T(), suite.repo.SetActive(user.ID, true), gorm.ErrRecordNotFound)
+}
+
+func (suite *UserRepositoryTestSuite) TestDeleteUserKeepsModerationEvidence() {
+    user := &models.User{Email: "test@example.com", PasswordHash: "testpass"}
+    reporter := &models.User{Email: "reporter@example.com", PasswordHash: "testpass"}
+    closed := &models.User{Email: "closed@example.com", PasswordHash: "testpass"}
+    suite.db.Create(user)
+    suite.db.Create(reporter)
+    suite.db.Create(closed)
+
+    suite.db.Create(&models.Report{ReporterID: reporter.ID, ReportedID: user.ID, Reason: models.ReportHarassment})
+    suite.db.Create(&models.Report{ReporterID: user.ID, ReportedID: closed.ID, Reason: models.ReportSpam, Status: models.ReportDismissed})
+    suite.db.Create(&models.Message{SenderID: user.ID, ReceiverID: reporter.ID, Content: "Evidence"})
+    suite.db.Create(&models.Message{SenderID: user.ID, ReceiverID: closed.ID, Content: "Not needed"})
+    suite.db.Create(&models.AuditLog{ActorID: reporter.ID, Action: "view_user", TargetType: "user", TargetID: user.ID})
+
+    err := suite.repo.Delete(user.ID)
+    assert.NoError(suite.T(), err)
+
+    var reports []models.Report
+    suite.db.Find(&reports)
+    assert.Len(suite.T(), reports, 2)
+
+    // Only messages with someone in an open report are kept, hidden
+    var messages []models.Message
+    suite.db.Find(&messages)
+    assert.Len(suite.T(), messages, 1)
+    assert.Equal(suite.T(), "Evidence", messages[0].Content)
+    assert.NotNil(suite.T(), messages[0].HiddenAt)
+
+    var audits int64
+    suite.db.Model(&models.AuditLog{}).Count(&audits)
+    assert.Equal(suite.T(), int64(1), audits)
+}
+
 func (suite *UserRepositoryTestSuite) TestDeleteNonExistentUser() {
     err := suite.repo.Delete(999) // Non-existent ID
     assert.Error(suite.T(), err)
@@ -0,0 +1,72 @@
+package repositories
+
+import (
+    "time"
+
+    "github.com/connectplus/models"
+    "gorm.io/gorm"
+)
+
+type UserTokenRepository interface {
+    Create(token *models.UserToken) error
+    FindByHash(hash string) (*models.UserToken, error)
+    // MarkUsed redeems the token. It returns gorm.ErrRecordNotFound if the
+    // token was already used, so concurrent redemptions succeed only once.
+    MarkUsed(id uint) error
+    // InvalidateForUser marks every unused token of the given purpose as used.
+    InvalidateForUser(userID uint, purpose models.TokenPurpose) error
+    CountSince(userID uint, purpose models.TokenPurpose, since time.Time) (int64, error)
+    FindLatest(userID uint, purpose models.TokenPurpose) (*models.UserToken, error)
+}
+
+type userTokenRepository struct {
+    db *gorm.DB
+}
+
+func NewUserTokenRepository(db *gorm.DB) UserTokenRepository {
+    return &userTokenRepository{db: db}
+}
+
+func (r *userTokenRepository) Create(token *models.UserToken) error {
+    return r.db.Create(token).Error
+}
+
+func (r *userTokenRepository) FindByHash(hash string) (*models.UserToken, error) {
+    var token models.UserToken
+    err := r.db.Where("token_hash = ?", hash).First(&token).Error
+    return &token, err
+}
+
+func (r *userTokenRepository) MarkUsed(id uint) error {
+    result := r.db.Model(&models.UserToken{}).
+        Where("id = ? AND used_at IS NULL", id).
+        Update("used_at", time.Now())
+    if result.Error != nil {
+        return result.Error
+    }
+    if result.RowsAffected == 0 {
+        return gorm.ErrRecordNotFound
+    }
+    return nil
+}
+
+func (r *userTokenRepository) InvalidateForUser(userID uint, purpose models.TokenPurpose) error {
+    return r.db.Model(&models.UserToken{}).
+        Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+        Update("used_at", time.Now()).Error
+}
+
+func (r *userTokenRepository) CountSince(userID uint, purpose models.TokenPurpose, since time.Time) (int64, error) {
+    var count int64
+    err := r.db.Model(&models.UserToken{}).
+        Where("user_id = ? AND purpose = ? AND created_at >= ?", userID, purpose, since).
+        Count(&count).Error
+    return count, err
+}
+
+func (r *userTokenRepository) FindLatest(userID uint, purpose models.TokenPurpose) (*models.UserToken, error) {
+    var token models.UserToken
+    err := r.db.Where("user_id = ? AND purpose = ?", userID, purpose).
+        Order("created_at desc").Order("id desc").First(&token).Error
+    return &token, err
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+    "testing"
+    "time"
+    
+    "github.com/connectplus/models"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/suite"
+    "gorm.io/driver/sqlite"
+    "gorm.io/gorm"
+)
+
+type UserTokenRepositoryTestSuite struct {
+    suite.Suite
+    db *gorm.DB
+    repo UserTokenRepository
+}
+
+func (suite *UserTokenRepositoryTestSuite) SetupTest() {
+    var err error
+    suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+    assert.NoError(suite.T(), err)
+    
+    // Migrate the schema for both User and UserToken
+    err = suite.db.AutoMigrate(&models.User{}, &models.UserToken{})
+    assert.NoError(suite.T(), err)
+    
+    suite.repo = NewUserTokenRepository(suite.db)
+}
+
+func (suite *UserTokenRepositoryTestSuite) TearDownTest() {
+    db, _ := suite.db.DB()
+    db.Close()
+}
+
+func (suite *UserTokenRepositoryTestSuite) newToken(userID uint, hash string) *models.UserToken {
+    token := &models.UserToken{
+        UserID:    userID,
+        Purpose:   models.TokenVerifyEmail,
+        TokenHash: hash,
+        ExpiresAt: time.Now().Add(time.Hour),
+    }
+    err := suite.repo.Create(token)
+    assert.NoError(suite.T(), err)
+    return token
+}
+
+func (suite *UserTokenRepositoryTestSuite) TestCreateAndFindByHash() {
+    token := suite.newToken(1, "hash1")
+    assert.NotZero(suite.T(), token.ID)
+    
+    found, err := suite.repo.FindByHash("hash1")
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), token.ID, found.ID)
+    assert.True(suite.T(), found.IsUsable(time.Now()))
+    
+    _, err = suite.repo.FindByHash("missing")
+    assert.Equal(suite.T(), gorm.ErrRecordNotFound, err)
+}
+
+func (suite *UserTokenRepositoryTestSuite) TestMarkUsedOnlyOnce() {
+    token := suite.newToken(1, "hash1")
+    
+    err := suite.repo.MarkUsed(token.ID)
+    assert.NoError(suite.T(), err)
+    
+    err = suite.repo.MarkUsed(token.ID)
+    assert.Equal(suite.T(), gorm.ErrRecordNotFound, err)
+    
+    found, err := suite.repo.FindByHash("hash1")
+    assert.NoError(suite.T(), err)
+    assert.False(suite.T(), found.IsUsable(time.Now()))
+}
+
+func (suite *UserTokenRepositoryTestSuite) TestInvalidateForUser() {
+    first := suite.newToken(1, "hash1")
+    other := suite.newToken(2, "hash2")
+    
+    err := suite.repo.InvalidateForUser(1, models.TokenVerifyEmail)
+    assert.NoError(suite.T(), err)
+    
+    found, _ := suite.repo.FindByHash(first.TokenHash)
+    assert.False(suite.T(), found.IsUsable(time.Now()))
+    found, _ = suite.repo.FindByHash(other.TokenHash)
+    assert.True(suite.T(), found.IsUsable(time.Now()))
+}
+
+func (suite *UserTokenRepositoryTestSuite) TestCountSinceAndFindLatest() {
+    old := &models.UserToken{
+        UserID:    1,
+        Purpose:   models.TokenVerifyEmail,
+        TokenHash: "old",
+        ExpiresAt: time.Now(),
+        CreatedAt: time.Now().Add(-48 * time.Hour),
+    }
+    suite.db.Create(old)
+    suite.newToken(1, "hash1")
+    latest := suite.newToken(1, "hash2")
+    
+    count, err := suite.repo.CountSince(1, models.TokenVerifyEmail, time.Now().Add(-24*time.Hour))
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), int64(2), count)
+    
+    found, err := suite.repo.FindLatest(1, models.TokenVerifyEmail)
+    assert.NoError(suite.T(), err)
+    assert.Equal(suite.T(), latest.ID, found.ID)
+    
+    _, err = suite.repo.FindLatest(999, models.TokenVerifyEmail)
+    assert.Equal(suite.T(), gorm.ErrRecordNotFound, err)
+}
+
+func (suite *UserTokenRepositoryTestSuite) TestExpiredTokenIsNotUsable() {
+    token := &models.UserToken{ExpiresAt: time.Now().Add(-time.Minute)}
+    assert.False(suite.T(), token.IsUsable(time.Now()))
+}
+
+func TestUserTokenRepositorySuite(t *testing.T) {
+    suite.Run(t, new(UserTokenRepositoryTestSuite))
+}
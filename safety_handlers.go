@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/connectplus/models"
+	"gorm.io/gorm"
+)
+
+const maxReportDetailsLength = 1000
+
+// ReportRequest represents the request payload for reporting a user
+// @swagger:model
+type ReportRequest struct {
+	// One of "spam", "harassment", "inappropriate_content", "fake_profile",
+	// "underage" or "other"
+	// required: true
+	// example: harassment
+	Reason models.ReportReason `json:"reason"`
+
+	// What happened, in the reporter's words. At most 1000 characters.
+	// example: Keeps sending messages after I asked them to stop
+	Details string `json:"details"`
+}
+
+// ReportResponse represents a report as seen by the user who filed it
+// @swagger:model
+type ReportResponse struct {
+	// example: 5
+	ID uint `json:"id"`
+
+	// example: 2
+	ReportedUserID uint `json:"reported_user_id"`
+
+	// example: harassment
+	Reason models.ReportReason `json:"reason"`
+
+	// example: open
+	Status models.ReportStatus `json:"status"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// targetUserID parses the user in the path, rejecting the caller themselves
+// and users who don't exist. It writes the error response and returns false
+// on failure.
+func targetUserID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	targetID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil || targetID == 0 || uint(targetID) == currentUserID(r) {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return 0, false
+	}
+
+	if _, err := userRepo.FindByID(uint(targetID)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return 0, false
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return 0, false
+	}
+	return uint(targetID), true
+}
+
+// blockUserHandler godoc
+// @Summary Block a user
+// @Description Block another user. From then on neither user is shown to the other in discovery or can swipe on or message the other, any pending or accepted match between them is ended and their conversation is hidden from both. Blocking someone twice has no further effect.
+// @Tags safety
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "The user to block"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/{id}/block [post]
+func blockUserHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	blockedID, ok := targetUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := blockRepo.Create(currentUserID(r), blockedID); err != nil {
+		http.Error(w, "Failed to block user", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reportUserHandler godoc
+// @Summary Report a user
+// @Description Report another user to the moderators. Reporting doesn't block the user; block them as well to stop all contact.
+// @Tags safety
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "The user to report"
+// @Param report body ReportRequest true "Report"
+// @Success 201 {object} ReportResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/{id}/report [post]
+func reportUserHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if !req.Reason.Valid() {
+		http.Error(w, "Invalid reason", http.StatusBadRequest)
+		return
+	}
+	req.Details = strings.TrimSpace(req.Details)
+	if utf8.RuneCountInString(req.Details) > maxReportDetailsLength {
+		http.Error(w, "Details must be at most 1000 characters", http.StatusBadRequest)
+		return
+	}
+
+	reportedID, ok := targetUserID(w, r)
+	if !ok {
+		return
+	}
+
+	report := &models.Report{
+		ReporterID: currentUserID(r),
+		ReportedID: reportedID,
+		Reason:     req.Reason,
+		Details:    req.Details,
+		Status:     models.ReportOpen,
+	}
+	if err := reportRepo.Create(report); err != nil {
+		http.Error(w, "Failed to report user", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ReportResponse{
+		ID:             report.ID,
+		ReportedUserID: report.ReportedID,
+		Reason:         report.Reason,
+		Status:         report.Status,
+		CreatedAt:      report.CreatedAt,
+	})
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/connectplus/models"
+	"gorm.io/gorm"
+)
+
+// SwipeRequest represents the request payload for liking or passing on a user
+// @swagger:model
+type SwipeRequest struct {
+	// ID of the user being swiped on
+	// required: true
+	// example: 42
+	TargetUserID uint `json:"target_user_id"`
+
+	// Swipe direction, either "like" or "pass"
+	// required: true
+	// example: like
+	Direction models.SwipeDirection `json:"direction"`
+}
+
+// SwipeResponse represents the result of a swipe
+// @swagger:model
+type SwipeResponse struct {
+	// Whether this swipe completed a mutual like
+	// example: true
+	Matched bool `json:"matched"`
+
+	// ID of the pending match created by this swipe, if any
+	// example: 7
+	MatchID uint `json:"match_id,omitempty"`
+}
+
+// createSwipeHandler godoc
+// @Summary Like or pass on a user
+// @Description Record a like or pass on another user. A like on someone who already liked the caller creates a pending match, which they then accept or decline. Users who unmatched can't swipe on each other until the re-match cooldown has passed.
+// @Tags swipes
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param swipe body SwipeRequest true "Swipe details"
+// @Success 200 {object} SwipeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /swipes [post]
+func createSwipeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SwipeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.Direction != models.SwipeLike && req.Direction != models.SwipePass {
+		http.Error(w, "Direction must be \"like\" or \"pass\"", http.StatusBadRequest)
+		return
+	}
+
+	userID := currentUserID(r)
+	if req.TargetUserID == 0 || req.TargetUserID == userID {
+		http.Error(w, "Invalid target user", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := userRepo.FindByID(req.TargetUserID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	// Blocked users are treated as gone in both directions
+	blocked, err := blockRepo.IsBlocked(userID, req.TargetUserID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if blocked {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	// Users who unmatched can't swipe on each other again until the
+	// cooldown has passed
+	existing, err := matchRepo.FindByUsers(userID, req.TargetUserID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	hasMatch := err == nil
+	if hasMatch && existing.Status == models.MatchUnmatched && existing.UnmatchedAt != nil &&
+		time.Since(*existing.UnmatchedAt) < cfg.RematchCooldown {
+		http.Error(w, "You recently unmatched this user", http.StatusConflict)
+		return
+	}
+
+	// Only a first like is news to the other user. Likes between users who
+	// already have a match, or that follow an earlier swipe on the same
+	// user, don't notify them again.
+	_, err = swipeRepo.FindBySwiperAndSwiped(userID, req.TargetUserID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	firstSwipe := err != nil
+
+	match, err := swipeRepo.Create(&models.Swipe{
+		SwiperID:  userID,
+		SwipedID:  req.TargetUserID,
+		Direction: req.Direction,
+	})
+	if err != nil {
+		http.Error(w, "Failed to record swipe", http.StatusInternalServerError)
+		return
+	}
+
+	resp := SwipeResponse{}
+	if match != nil {
+		resp.Matched = true
+		resp.MatchID = match.ID
+		notifyNewMatch(match, userID)
+	} else if req.Direction == models.SwipeLike && firstSwipe && !hasMatch {
+		notifyLikeReceived(req.TargetUserID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
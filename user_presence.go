@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/connectplus/models"
+	"github.com/connectplus/presence"
+)
+
+// presenceStore tracks who is online, from chat connections and
+// authenticated requests
+var presenceStore presence.Store
+
+// userPresence returns the user's presence, falling back to the last-active
+// time saved on their row when the store has no record of them
+func userPresence(user *models.User) presence.Presence {
+	if p, ok := presenceStore.Get(user.ID); ok {
+		return p
+	}
+	var p presence.Presence
+	if user.LastActiveAt != nil {
+		p.LastActiveAt = *user.LastActiveAt
+	}
+	return p
+}
+
+// runPresenceFlush saves users' last-active times every interval, so a user
+// making many requests costs one write per interval. It never returns, so
+// start it in its own goroutine.
+func runPresenceFlush(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		lastActive := presenceStore.Drain()
+		if len(lastActive) == 0 {
+			continue
+		}
+		if err := userRepo.SetLastActive(lastActive); err != nil {
+			log.Printf("Failed to save last-active times for %d users: %v", len(lastActive), err)
+			// Try them again on the next tick rather than losing them
+			presenceStore.Restore(lastActive)
+		}
+	}
+}
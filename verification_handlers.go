@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/connectplus/mailer"
+	"github.com/connectplus/models"
+	"github.com/dgrijalva/jwt-go"
+	"gorm.io/gorm"
+)
+
+const (
+	verificationTokenTTL = 24 * time.Hour
+	// resendCooldown and maxResendsPerDay throttle verification emails so the
+	// resend endpoint can't be used to flood an inbox
+	resendCooldown   = time.Minute
+	maxResendsPerDay = 5
+	mailTimeout      = 10 * time.Second
+)
+
+// newSignedUserToken returns a JWT for a single-use mailed token. The
+// signature rejects forged or tampered links before any database lookup;
+// single use is enforced by the stored hash.
+func newSignedUserToken(userID uint, purpose models.TokenPurpose, ttl time.Duration) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID,
+		"purpose": string(purpose),
+		"jti":     base64.RawURLEncoding.EncodeToString(nonce),
+		"exp":     time.Now().Add(ttl).Unix(),
+	})
+	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+}
+
+// redeemSignedUserToken checks a token from newSignedUserToken and marks it
+// used, returning the ID of the user it was issued to
+func redeemSignedUserToken(tokenString string, purpose models.TokenPurpose) (uint, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	})
+	if err != nil || !token.Valid {
+		return 0, errInvalidUserToken
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != string(purpose) {
+		return 0, errInvalidUserToken
+	}
+
+	stored, err := userTokenRepo.FindByHash(hashToken(tokenString))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, errInvalidUserToken
+	}
+	if err != nil {
+		return 0, err
+	}
+	if stored.Purpose != purpose || !stored.IsUsable(time.Now()) {
+		return 0, errInvalidUserToken
+	}
+
+	if err := userTokenRepo.MarkUsed(stored.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, errInvalidUserToken
+		}
+		return 0, err
+	}
+	return stored.UserID, nil
+}
+
+var errInvalidUserToken = errors.New("invalid or expired token")
+
+// issueUserToken replaces any outstanding token of the given purpose for the
+// user with a new one and returns it
+func issueUserToken(userID uint, purpose models.TokenPurpose, ttl time.Duration) (string, error) {
+	if err := userTokenRepo.InvalidateForUser(userID, purpose); err != nil {
+		return "", err
+	}
+
+	token, err := newSignedUserToken(userID, purpose, ttl)
+	if err != nil {
+		return "", err
+	}
+	err = userTokenRepo.Create(&models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// canMailToken reports whether another token of the given purpose may be
+// mailed to the user, allowing one per resendCooldown and maxResendsPerDay
+func canMailToken(userID uint, purpose models.TokenPurpose) (bool, error) {
+	latest, err := userTokenRepo.FindLatest(userID, purpose)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if time.Since(latest.CreatedAt) < resendCooldown {
+		return false, nil
+	}
+
+	sent, err := userTokenRepo.CountSince(userID, purpose, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		return false, err
+	}
+	return sent < maxResendsPerDay, nil
+}
+
+// sendVerificationEmail mails the user a new verification link
+func sendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, err := issueUserToken(user.ID, models.TokenVerifyEmail, verificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/auth/verify?token=%s", cfg.BaseURL, url.QueryEscape(token))
+	ctx, cancel := context.WithTimeout(ctx, mailTimeout)
+	defer cancel()
+	return mail.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your Connect+ email address",
+		Body: fmt.Sprintf("Welcome to Connect+!\n\nConfirm your email address by opening this link:\n\n%s\n\n"+
+			"The link expires in 24 hours. If you didn't create an account, you can ignore this email.", link),
+	})
+}
+
+// verifyEmailHandler godoc
+// @Summary Verify email address
+// @Description Confirm the email address of the account a verification link was sent to. Each link works once.
+// @Tags auth
+// @Produce  json
+// @Param token query string true "Verification token from the email link"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/verify [get]
+func verifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tokenString := r.URL.Query().Get("token")
+	if tokenString == "" {
+		http.Error(w, "Missing token", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := redeemSignedUserToken(tokenString, models.TokenVerifyEmail)
+	if errors.Is(err, errInvalidUserToken) {
+		http.Error(w, "Invalid or expired verification link", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	result := db.Model(&models.User{}).Where("id = ?", userID).Update("is_verified", true)
+	if result.Error != nil {
+		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Email verified successfully",
+	})
+}
+
+// resendVerificationHandler godoc
+// @Summary Resend verification email
+// @Description Send a new verification link to the authenticated user. Earlier links stop working. Limited to one per minute and five per day.
+// @Tags auth
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/verify/resend [post]
+func resendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := userRepo.FindByID(currentUserID(r))
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if user.IsVerified {
+		http.Error(w, "Email is already verified", http.StatusConflict)
+		return
+	}
+
+	allowed, err := canMailToken(user.ID, models.TokenVerifyEmail)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.Error(w, "Too many verification emails requested, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	if err := sendVerificationEmail(r.Context(), user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		http.Error(w, "Failed to send verification email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Verification email sent",
+	})
+}
+
+// requireVerified rejects users who have not verified their email address
+// when the REQUIRE_VERIFIED_EMAIL switch is on. It must run after authMiddleware.
+func requireVerified(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.RequireVerifiedEmail {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, err := userRepo.FindByID(currentUserID(r))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				http.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if !user.IsVerified {
+			http.Error(w, "Email address must be verified", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}